@@ -1,15 +1,186 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	gofanatical "github.com/Feuerlord2/Fanatical-RSS-Site/pkg"
 )
 
+const usage = `Usage: gofanatical [command] [flags]
+
+Commands:
+  generate         fetch bundles and write the feeds (default)
+  serve            regenerate on a schedule and serve the output over HTTP
+  list             print the current bundles
+  show <slug>      print the details of one bundle
+  validate <file>  check RSS files for problems feed readers would reject
+
+Run "gofanatical <command> -h" for the flags of a command.
+`
+
 func main() {
-	if err := gofanatical.Run(); err != nil {
+	command, args := "generate", os.Args[1:]
+	// Flags without a command keep meaning generate, as before subcommands.
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "generate":
+		generate(args)
+	case "serve":
+		serve(args)
+	case "list":
+		list(args)
+	case "show":
+		show(args)
+	case "validate":
+		validate(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// generate runs the pipeline once.
+func generate(args []string) {
+	var opts gofanatical.Options
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	fs.StringVar(&opts.OutDir, "out", "docs", "directory to write feeds to")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "write nothing; print what would change in each feed")
+	fs.StringVar(&opts.MetricsFile, "metrics-file", "", "write Prometheus metrics to this file (textfile collector)")
+	fs.Parse(args)
+
+	result, err := gofanatical.Run(opts)
+	if opts.DryRun {
+		result.WriteDiffSummary(os.Stdout)
+	}
+	if err != nil {
 		slog.Error("feed generation failed", "error", err)
 		os.Exit(1)
 	}
 }
+
+// serve runs the pipeline on a schedule and serves the output over HTTP
+// until interrupted.
+func serve(args []string) {
+	var opts gofanatical.ServeOptions
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&opts.Run.OutDir, "out", "docs", "directory to write feeds to")
+	fs.StringVar(&opts.Addr, "addr", ":8080", "address to listen on")
+	fs.DurationVar(&opts.Interval, "interval", 0, "time between runs (default 6h)")
+	fs.DurationVar(&opts.Jitter, "jitter", 0, "random extra delay of up to this much per run")
+	fs.DurationVar(&opts.StaleAfter, "stale-after", 0, "report unhealthy at /healthz once the last fetch is older (default 2×interval+jitter)")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := gofanatical.Serve(ctx, opts); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// list prints the current bundles, optionally filtered.
+func list(args []string) {
+	var filter gofanatical.BundleFilter
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	format := fs.String("format", gofanatical.FormatTable, "output format: table, csv, or json")
+	fs.StringVar(&filter.Category, "category", "", "only these categories, comma-separated (books, games, software)")
+	fs.StringVar(&filter.MinPrice, "min-price", "", "only bundles costing at least this much")
+	fs.StringVar(&filter.MaxPrice, "max-price", "", "only bundles costing at most this much")
+	fs.StringVar(&filter.Query, "query", "", "only bundles matching this feed query expression")
+	fs.Parse(args)
+
+	// Reject bad flags before the fetch, which retries for a while.
+	switch *format {
+	case gofanatical.FormatTable, gofanatical.FormatCSV, gofanatical.FormatJSON:
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q: use table, csv, or json\n", *format)
+		os.Exit(2)
+	}
+	if _, err := filter.Apply(nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	site, err := gofanatical.LoadSite()
+	var bundles []gofanatical.FanaticalBundle
+	if err == nil {
+		bundles, err = gofanatical.CurrentBundles(gofanatical.Options{})
+	}
+	if err == nil {
+		bundles, err = filter.Apply(bundles)
+	}
+	if err == nil {
+		err = site.WriteBundles(os.Stdout, bundles, *format)
+	}
+	if err != nil {
+		slog.Error("listing bundles failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// show prints one bundle.
+func show(args []string) {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: gofanatical show <slug>")
+		os.Exit(2)
+	}
+
+	site, err := gofanatical.LoadSite()
+	if err != nil {
+		slog.Error("loading config failed", "error", err)
+		os.Exit(1)
+	}
+	bundles, err := gofanatical.CurrentBundles(gofanatical.Options{})
+	if err != nil {
+		slog.Error("fetching bundles failed", "error", err)
+		os.Exit(1)
+	}
+	bundle, ok := gofanatical.FindBundle(bundles, fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "no current bundle %q\n", fs.Arg(0))
+		os.Exit(1)
+	}
+	if err := site.WriteBundleDetails(os.Stdout, bundle); err != nil {
+		slog.Error("writing bundle failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// validate checks feed files and exits non-zero if any has problems.
+func validate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gofanatical validate <file>...")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range fs.Args() {
+		if err := gofanatical.ValidateFeedFile(path); err != nil {
+			failed = true
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Printf("%s: %s\n", path, line)
+			}
+			continue
+		}
+		fmt.Printf("%s: ok\n", path)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,232 @@
+package gofanatical
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// The JSON API serves the latest snapshot in server mode. Bundles use the
+// same representation as webhook payloads.
+
+const (
+	defaultAPIPageSize = 50
+	maxAPIPageSize     = 200
+)
+
+type apiBundle struct {
+	webhookBundle
+	Description string `json:"description,omitempty"`
+}
+
+type apiBundleList struct {
+	GeneratedAt time.Time   `json:"generated_at"`
+	Total       int         `json:"total"`
+	Page        int         `json:"page"`
+	PerPage     int         `json:"per_page"`
+	Bundles     []apiBundle `json:"bundles"`
+}
+
+type apiCategory struct {
+	Name    string `json:"name"`
+	Bundles int    `json:"bundles"`
+}
+
+type apiCategoryList struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Categories  []apiCategory `json:"categories"`
+}
+
+func apiBundleFor(b FanaticalBundle, site Site) apiBundle {
+	return apiBundle{webhookBundle: webhookBundleFor(b, site), Description: b.Description}
+}
+
+// apiBundleSorts are the values of the sort parameter with their default
+// direction.
+var apiBundleSorts = map[string]struct {
+	compare func(a, b FanaticalBundle) int
+	desc    bool
+}{
+	"discount": {func(a, b FanaticalBundle) int { return cmp.Compare(a.Price.Discount, b.Price.Discount) }, true},
+	"price":    {func(a, b FanaticalBundle) int { return cmp.Compare(a.Price.Amount, b.Price.Amount) }, false},
+	"end_date": {func(a, b FanaticalBundle) int { return a.EndDate.Compare(b.EndDate) }, false},
+}
+
+// apiListQuery is a parsed /api/bundles request.
+type apiListQuery struct {
+	match   query
+	sort    string
+	desc    bool
+	page    int
+	perPage int
+}
+
+// parseAPIListQuery accepts the /feed.rss filter parameters plus sort,
+// order, page, and per_page.
+func parseAPIListQuery(params url.Values) (apiListQuery, error) {
+	q := apiListQuery{page: 1, perPage: defaultAPIPageSize}
+	filters := url.Values{}
+	for name, values := range params {
+		switch name {
+		case "sort", "order", "page", "per_page":
+		default:
+			filters[name] = values
+		}
+	}
+	expr, err := dynamicFeedQuery(filters)
+	if err != nil {
+		return q, err
+	}
+	if q.match, err = compileQuery(expr); err != nil {
+		return q, err
+	}
+
+	if q.sort = params.Get("sort"); q.sort != "" {
+		s, ok := apiBundleSorts[q.sort]
+		if !ok {
+			return q, fmt.Errorf("invalid sort %q: use discount, price, or end_date", q.sort)
+		}
+		q.desc = s.desc
+	}
+	switch order := params.Get("order"); order {
+	case "":
+	case "asc", "desc":
+		q.desc = order == "desc"
+	default:
+		return q, fmt.Errorf("invalid order %q: use asc or desc", order)
+	}
+
+	positive := func(name string, v *int, max int) error {
+		raw := params.Get(name)
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > max {
+			return fmt.Errorf("invalid %s %q", name, raw)
+		}
+		*v = n
+		return nil
+	}
+	if err := positive("page", &q.page, 1e6); err != nil {
+		return q, err
+	}
+	if err := positive("per_page", &q.perPage, maxAPIPageSize); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// list filters, sorts, and paginates bundles. Without a sort parameter,
+// and between equal keys, bundles are in feed order: newest first.
+func (q apiListQuery) list(bundles []FanaticalBundle, at time.Time, site Site) apiBundleList {
+	var matched []FanaticalBundle
+	for _, b := range bundles {
+		if q.match(b) {
+			matched = append(matched, b)
+		}
+	}
+	slices.SortFunc(matched, func(a, b FanaticalBundle) int {
+		if q.sort != "" {
+			c := apiBundleSorts[q.sort].compare(a, b)
+			if q.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		if c := b.StartDate.Compare(a.StartDate); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Slug, b.Slug)
+	})
+
+	list := apiBundleList{GeneratedAt: at.UTC(), Total: len(matched), Page: q.page, PerPage: q.perPage, Bundles: []apiBundle{}}
+	start := min((q.page-1)*q.perPage, len(matched))
+	end := min(start+q.perPage, len(matched))
+	for _, b := range matched[start:end] {
+		list.Bundles = append(list.Bundles, apiBundleFor(b, site))
+	}
+	return list
+}
+
+// apiSnapshot returns the current snapshot, or answers 503 and returns
+// nil before the first fetch.
+func (s *Server) apiSnapshot(w http.ResponseWriter) *snapshot {
+	snap := s.snapshot()
+	if snap == nil {
+		w.Header().Set("Retry-After", "60")
+		writeAPIError(w, http.StatusServiceUnavailable, "no bundles fetched yet")
+	}
+	return snap
+}
+
+func (s *Server) serveAPIBundles(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAPIListQuery(r.URL.Query())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if snap := s.apiSnapshot(w); snap != nil {
+		writeAPIJSON(w, q.list(snap.bundles, snap.at, snap.site))
+	}
+}
+
+// serveAPIBundle answers /api/bundles/{slug}.
+func (s *Server) serveAPIBundle(w http.ResponseWriter, r *http.Request) {
+	snap := s.apiSnapshot(w)
+	if snap == nil {
+		return
+	}
+	slug := r.PathValue("slug")
+	b, ok := FindBundle(snap.bundles, slug)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("no current bundle %q", slug))
+		return
+	}
+	writeAPIJSON(w, apiBundleFor(b, snap.site))
+}
+
+func (s *Server) serveAPICategories(w http.ResponseWriter, r *http.Request) {
+	snap := s.apiSnapshot(w)
+	if snap == nil {
+		return
+	}
+	list := apiCategoryList{GeneratedAt: snap.at.UTC()}
+	for _, name := range categories {
+		c := apiCategory{Name: name}
+		for _, b := range snap.bundles {
+			if b.Category == name {
+				c.Bundles++
+			}
+		}
+		list.Categories = append(list.Categories, c)
+	}
+	writeAPIJSON(w, list)
+}
+
+func writeAPIJSON(w http.ResponseWriter, v any) {
+	writeAPIResponse(w, http.StatusOK, v)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, msg string) {
+	writeAPIResponse(w, code, map[string]string{"error": msg})
+}
+
+// writeAPIResponse sends v as JSON. The API is public and read-only, so
+// any origin may read it, e.g. browser extensions and web pages.
+func writeAPIResponse(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if code == http.StatusOK {
+		w.Header().Set("Cache-Control", defaultCacheControl)
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
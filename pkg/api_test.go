@@ -0,0 +1,105 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestServerAPI(t *testing.T) {
+	s := &Server{}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	get := func(path string, wantCode int, v any) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantCode {
+			t.Fatalf("GET %s: status %d, want %d", path, resp.StatusCode, wantCode)
+		}
+		if resp.Header.Get("Content-Type") != "application/json; charset=utf-8" || resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("GET %s: headers %v", path, resp.Header)
+		}
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+		}
+	}
+
+	get("/api/bundles", http.StatusServiceUnavailable, nil)
+
+	cheap := testBundle("cheap", time.Unix(1000, 0))
+	cheap.Category = "games"
+	cheap.Price.Amount = 1.99
+	cheap.Price.Discount = 90
+	cheap.EndDate = time.Unix(9000, 0)
+	mid := testBundle("mid", time.Unix(2000, 0))
+	mid.Category = "games"
+	mid.EndDate = time.Unix(7000, 0)
+	pricey := testBundle("pricey", time.Unix(3000, 0))
+	pricey.Category = "games"
+	pricey.Price.Amount = 19.99
+	pricey.Price.Discount = 20
+	pricey.EndDate = time.Unix(8000, 0)
+	pricey.Description = "Ten premium games."
+	book := testBundle("book", time.Unix(4000, 0))
+	book.Category = "books"
+	s.setSnapshot([]FanaticalBundle{cheap, mid, pricey, book}, time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC))
+
+	slugs := func(list apiBundleList) []string {
+		var out []string
+		for _, b := range list.Bundles {
+			out = append(out, b.Slug)
+		}
+		return out
+	}
+	for _, tt := range []struct {
+		path  string
+		want  []string
+		total int
+	}{
+		{"/api/bundles", []string{"book", "pricey", "mid", "cheap"}, 4},
+		{"/api/bundles?category=games&sort=price", []string{"cheap", "mid", "pricey"}, 3},
+		{"/api/bundles?category=games&sort=discount", []string{"cheap", "mid", "pricey"}, 3},
+		{"/api/bundles?category=games&sort=end_date&order=desc", []string{"cheap", "pricey", "mid"}, 3},
+		{"/api/bundles?min_discount=50&sort=price&per_page=2&page=2", []string{"mid"}, 3},
+		{"/api/bundles?page=9", nil, 4},
+	} {
+		var list apiBundleList
+		get(tt.path, http.StatusOK, &list)
+		if got := slugs(list); list.Total != tt.total || !slices.Equal(got, tt.want) {
+			t.Errorf("GET %s: total %d, slugs %v, want %d %v", tt.path, list.Total, got, tt.total, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"?sort=name", "?order=up", "?per_page=1000", "?page=0", "?colour=red"} {
+		get("/api/bundles"+bad, http.StatusBadRequest, nil)
+	}
+
+	var bundle apiBundle
+	get("/api/bundles/pricey", http.StatusOK, &bundle)
+	if bundle.GUID != "fanatical-pricey-3000" || bundle.Price.Amount != 19.99 || bundle.Description != pricey.Description {
+		t.Errorf("bundle: %+v", bundle)
+	}
+	get("/api/bundles/gone", http.StatusNotFound, nil)
+
+	var cats apiCategoryList
+	get("/api/categories", http.StatusOK, &cats)
+	want := []apiCategory{{"books", 1}, {"games", 3}, {"software", 0}}
+	if len(cats.Categories) != len(want) {
+		t.Fatalf("categories: %+v", cats.Categories)
+	}
+	for i := range want {
+		if cats.Categories[i] != want[i] {
+			t.Errorf("categories: %+v, want %+v", cats.Categories, want)
+		}
+	}
+}
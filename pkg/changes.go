@@ -0,0 +1,165 @@
+package gofanatical
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// changesFeedName is the file name (docs/changes.rss) of the update feed.
+const changesFeedName = "changes"
+
+// Change kinds reported in the changes feed.
+const (
+	changePrice    = "price"
+	changeExtended = "extended"
+)
+
+// bundleChange is one price change or end-date change of a live deal.
+type bundleChange struct {
+	Kind   string
+	At     time.Time
+	Bundle FanaticalBundle
+	// From and To are the prices of a price change, PrevEnd and End the
+	// end dates of an end-date change.
+	From, To     Price
+	PrevEnd, End time.Time
+}
+
+// summary describes the change in locale l, e.g. "Price dropped from
+// $9.99 to $4.99".
+func (c bundleChange) summary(l locale) string {
+	switch c.Kind {
+	case changePrice:
+		format := l.priceDropped
+		if c.To.Amount > c.From.Amount {
+			format = l.priceRose
+		}
+		return fmt.Sprintf(format, l.formatAmount(c.From.Currency, c.From.Amount), l.formatAmount(c.To.Currency, c.To.Amount))
+	default:
+		format := l.extendedUntil
+		if c.End.Before(c.PrevEnd) {
+			format = l.nowEnds
+		}
+		return fmt.Sprintf(format, l.formatDate(c.End))
+	}
+}
+
+// guid identifies the change itself, not the deal, so the main feeds'
+// GUID contract is untouched and a change is delivered exactly once.
+func (c bundleChange) guid() string {
+	return fmt.Sprintf("fanatical-%s-%d-%s-%d", c.Bundle.Slug, c.Bundle.StartDate.Unix(), c.Kind, c.At.Unix())
+}
+
+// collectChanges derives the price and end-date changes of the currently
+// active bundles from their history. The result depends only on the
+// history log and the snapshot, never on the current time, so the changes
+// feed stays byte-identical between runs with no new changes.
+func collectChanges(h *History, bundles []FanaticalBundle) []bundleChange {
+	var changes []bundleChange
+
+	for _, b := range bundles {
+		rec, ok := h.Record(b.Slug, b.StartDate)
+		if !ok {
+			continue
+		}
+
+		for i := 1; i < len(rec.Prices); i++ {
+			prev, cur := rec.Prices[i-1].Price, rec.Prices[i].Price
+			if prev.Amount == cur.Amount && prev.Currency == cur.Currency {
+				// Discount-only changes (e.g. a new list price) aren't news.
+				continue
+			}
+			changes = append(changes, bundleChange{
+				Kind:   changePrice,
+				At:     rec.Prices[i].At,
+				Bundle: b,
+				From:   prev,
+				To:     cur,
+			})
+		}
+
+		for i := 1; i < len(rec.EndDates); i++ {
+			changes = append(changes, bundleChange{
+				Kind:    changeExtended,
+				At:      rec.EndDates[i].At,
+				Bundle:  b,
+				PrevEnd: rec.EndDates[i-1].EndDate,
+				End:     rec.EndDates[i].EndDate,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].At.Equal(changes[j].At) {
+			return changes[i].At.After(changes[j].At)
+		}
+		return changes[i].guid() < changes[j].guid()
+	})
+
+	return changes
+}
+
+// changesFeeds returns the changes feed of the site followed by one per
+// extra site locale, named changes.<locale> like the other localized feeds.
+func changesFeeds(site Site) []FeedDefinition {
+	defs := []FeedDefinition{changesFeed(site, changesFeedName)}
+	for _, code := range site.Locales {
+		defs = append(defs, changesFeed(site.inLocale(code), changesFeedName+"."+code))
+	}
+	return defs
+}
+
+// changesFeed returns the definition of a changes feed. It has no query;
+// its items come from the history store.
+func changesFeed(site Site, name string) FeedDefinition {
+	def := FeedDefinition{
+		Name:        name,
+		Title:       fmt.Sprintf(site.locale().changesTitle, site.withDefaults().Name),
+		Description: site.locale().changesDesc,
+		site:        site,
+	}
+	site.applyFeedMetadata(&def)
+	return def
+}
+
+// createChangesFeed renders the changes as their own feed. Each item links
+// to the deal and carries the full deal content below the change summary.
+func createChangesFeed(changes []bundleChange, def FeedDefinition) feeds.Feed {
+	feed := def.channel()
+
+	feed.Items = make([]*feeds.Item, len(changes))
+	for idx, change := range changes {
+		summary := change.summary(def.site.locale())
+		feed.Items[idx] = &feeds.Item{
+			Title:       fmt.Sprintf("%s: %s", change.Bundle.Title, summary),
+			Link:        &feeds.Link{Href: def.site.bundleURL(change.Bundle)},
+			Content:     fmt.Sprintf("<p><strong>%s</strong></p>\n", html.EscapeString(summary)) + def.itemContent(change.Bundle),
+			Created:     change.At,
+			Description: summary,
+			Id:          change.guid(),
+		}
+	}
+
+	if len(changes) > 0 {
+		feed.Created = changes[0].At
+	}
+
+	return feed
+}
+
+// formatAmount renders a price like the item content does.
+func formatAmount(currency string, amount float64) string {
+	return locales[defaultLocale].formatAmount(currency, amount)
+}
+
+// formatAmount renders a price like the item content in locale l does.
+func (l locale) formatAmount(currency string, amount float64) string {
+	if amount == 0 {
+		return l.labels.Free
+	}
+	return fmt.Sprintf("%s%.2f", currencySymbol(currency), amount)
+}
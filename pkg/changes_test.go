@@ -0,0 +1,79 @@
+package gofanatical
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectChangesReportsDropsAndExtensions(t *testing.T) {
+	h, err := openHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deal := testBundle("deal", time.Unix(1000, 0))
+	deal.Price = Price{Currency: "USD", Amount: 9.99, Original: 19.99, Discount: 50}
+	if _, err := h.Observe([]FanaticalBundle{deal}, time.Unix(5000, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	deal.Price = Price{Currency: "USD", Amount: 4.99, Original: 19.99, Discount: 75}
+	deal.EndDate = time.Date(2030, time.March, 1, 18, 0, 0, 0, time.UTC)
+	if _, err := h.Observe([]FanaticalBundle{deal}, time.Unix(6000, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := collectChanges(h, []FanaticalBundle{deal})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+
+	for code, want := range map[string][]string{
+		"en": {"Price dropped from $9.99 to $4.99", "Extended until March 1, 2030 18:00 UTC"},
+		"de": {"Preis gesenkt von $9.99 auf $4.99", "Verlängert bis 1. März 2030 18:00 UTC"},
+	} {
+		summaries := changes[0].summary(locales[code]) + "|" + changes[1].summary(locales[code])
+		for _, w := range want {
+			if !strings.Contains(summaries, w) {
+				t.Errorf("%s: missing %q in %q", code, w, summaries)
+			}
+		}
+	}
+
+	feed := createChangesFeed(changes, changesFeed(defaultSite, changesFeedName))
+	for _, item := range feed.Items {
+		// Change GUIDs must never collide with the deal GUID in the main feeds.
+		if item.Id == "fanatical-deal-1000" || !strings.HasPrefix(item.Id, "fanatical-deal-1000-") {
+			t.Errorf("unexpected change GUID %q", item.Id)
+		}
+	}
+	if feed.Items[0].Id == feed.Items[1].Id {
+		t.Error("price and extension changes share a GUID")
+	}
+	if !feed.Created.Equal(time.Unix(6000, 0)) {
+		t.Errorf("feed.Created = %v, want time of the newest change", feed.Created)
+	}
+}
+
+func TestCollectChangesIgnoresUnchangedAndInactive(t *testing.T) {
+	h, err := openHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deal := testBundle("quiet", time.Unix(1000, 0))
+	if _, err := h.Observe([]FanaticalBundle{deal}, time.Unix(5000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Observe([]FanaticalBundle{deal}, time.Unix(6000, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := collectChanges(h, []FanaticalBundle{deal}); len(changes) != 0 {
+		t.Errorf("unchanged deal produced changes: %+v", changes)
+	}
+	if changes := collectChanges(h, nil); len(changes) != 0 {
+		t.Errorf("deals missing from the snapshot produced changes: %+v", changes)
+	}
+}
@@ -0,0 +1,267 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// defaultConfigPath is read when GOFANATICAL_CONFIG is unset. A missing
+// file at the default path is not an error — the built-in feeds still run.
+const defaultConfigPath = "gofanatical.json"
+
+// Config is the optional user configuration file.
+type Config struct {
+	Feeds []FeedDefinition `json:"feeds"`
+	// HistoryFile enables the bundle history store (see history.go).
+	// Empty disables it.
+	HistoryFile string `json:"history_file"`
+	// ChangesFeed writes docs/changes.rss with price drops and extensions.
+	// It needs the history store.
+	ChangesFeed bool `json:"changes_feed"`
+	// S3 publishes to an S3-compatible object store instead of the local
+	// output directory.
+	S3 *S3Config `json:"s3"`
+	// Gzip also publishes a precompressed <feed>.rss.gz next to every
+	// feed, for gzip_static-style serving.
+	Gzip bool `json:"gzip"`
+	// Limits caps every feed; FeedLimits overrides it per feed name.
+	Limits     FeedLimits            `json:"limits"`
+	FeedLimits map[string]FeedLimits `json:"feed_limits"`
+	// Discord announces new bundles to a Discord webhook.
+	Discord *DiscordConfig `json:"discord"`
+	// Webhooks posts signed JSON change events to arbitrary endpoints.
+	Webhooks *WebhookConfig `json:"webhooks"`
+	// Email sends a digest of new bundles via SMTP.
+	Email *EmailConfig `json:"email"`
+	// Mastodon posts new bundles as statuses.
+	Mastodon *MastodonConfig `json:"mastodon"`
+	// Site is the identity the feeds are published under.
+	Site Site `json:"site"`
+	// Watchlists each get a personal feed and optional alerts.
+	Watchlists []Watchlist `json:"watchlists"`
+}
+
+// FeedDefinition describes one output feed: which bundles go into it and
+// which file it is written to (docs/<Name>.rss).
+type FeedDefinition struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Query       string `json:"query"`
+	// Image is the channel image (logo); Language the channel language.
+	// Empty means the site-wide value.
+	Image    string `json:"image"`
+	Language string `json:"language"`
+	// ContentTemplate and DescriptionTemplate are html/template files
+	// rendering each item's content and description from an ItemData.
+	// Empty means the built-in content and the bundle description.
+	ContentTemplate     string `json:"content_template"`
+	DescriptionTemplate string `json:"description_template"`
+
+	match       query
+	site        Site
+	content     *template.Template
+	description *template.Template
+}
+
+var feedNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// loadConfig reads the config file named by GOFANATICAL_CONFIG, falling
+// back to defaultConfigPath. Every feed query is compiled here so that a
+// broken expression fails the run before anything is written.
+func loadConfig() (Config, error) {
+	path := os.Getenv("GOFANATICAL_CONFIG")
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath
+	}
+
+	cfg := Config{Site: defaultSite}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	// A chosen main locale also sets the channel language of the main
+	// feeds, unless the site names one.
+	if cfg.Site.Locale != "" && cfg.Site.Language == "" {
+		cfg.Site.Language = locales[cfg.Site.Locale].language
+	}
+	cfg.Site = cfg.Site.withDefaults()
+	for _, code := range append([]string{cfg.Site.Locale}, cfg.Site.Locales...) {
+		if _, err := lookupLocale(code); err != nil {
+			return cfg, fmt.Errorf("config %s: site: %w", path, err)
+		}
+	}
+	if slices.Contains(cfg.Site.Locales, cfg.Site.Locale) {
+		return cfg, fmt.Errorf("config %s: site.locales: %q is already the main locale", path, cfg.Site.Locale)
+	}
+
+	if cfg.ChangesFeed && cfg.HistoryFile == "" {
+		return cfg, fmt.Errorf("config %s: changes_feed requires history_file", path)
+	}
+
+	for i := range cfg.Feeds {
+		def := &cfg.Feeds[i]
+		if !feedNamePattern.MatchString(def.Name) {
+			return cfg, fmt.Errorf("feed %d: invalid name %q (use lowercase letters, digits, '.', '-', '_')", i, def.Name)
+		}
+		if def.match, err = compileQuery(def.Query); err != nil {
+			return cfg, fmt.Errorf("feed %s: invalid query: %w", def.Name, err)
+		}
+		if def.Title == "" {
+			def.Title = cfg.Site.Name + ": " + def.Name
+		}
+		if def.Description == "" {
+			def.Description = "Fanatical bundles matching: " + def.Query
+		}
+	}
+	for _, w := range cfg.Watchlists {
+		if err := w.validate(); err != nil {
+			return cfg, err
+		}
+	}
+	// Only the history store tells a new bundle from one a limit let back
+	// into the feed; see collectFeedEvents.
+	if cfg.HistoryFile == "" {
+		for _, name := range cfg.notifiedFeeds() {
+			if cfg.limitsFor(name) != (FeedLimits{}) {
+				return cfg, fmt.Errorf("config %s: limits on feed %s with notifiers require history_file", path, name)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// siteFeedDefinitions returns the feeds of feedDefinitions for one
+// locale of the site.
+func siteFeedDefinitions(cfg Config, site Site, now time.Time) ([]FeedDefinition, error) {
+	var defs []FeedDefinition
+	for _, category := range categories {
+		defs = append(defs, categoryFeed(site, category))
+	}
+	defs = append(defs, endingSoonFeed(site, now))
+	defs = append(defs, cfg.Feeds...)
+	for _, w := range cfg.Watchlists {
+		def, err := watchlistFeed(w, site)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	for i := range defs {
+		defs[i].site = site
+	}
+	return defs, nil
+}
+
+// loadTemplates loads the item templates configured for def.
+func (def *FeedDefinition) loadTemplates() error {
+	var err error
+	if def.ContentTemplate != "" {
+		if def.content, err = loadItemTemplate(def.ContentTemplate); err != nil {
+			return fmt.Errorf("feed %s: content template: %w", def.Name, err)
+		}
+	}
+	if def.DescriptionTemplate != "" {
+		if def.description, err = loadItemTemplate(def.DescriptionTemplate); err != nil {
+			return fmt.Errorf("feed %s: description template: %w", def.Name, err)
+		}
+	}
+	return nil
+}
+
+// categoryFeed returns the built-in definition for one of the fixed
+// category feeds.
+func categoryFeed(site Site, category string) FeedDefinition {
+	return FeedDefinition{
+		Name:        category,
+		Title:       fmt.Sprintf(site.locale().categoryTitles[category], site.withDefaults().Name),
+		Description: site.locale().categoryDescriptions[category],
+		Query:       fmt.Sprintf("category == %q", category),
+		match:       func(b FanaticalBundle) bool { return b.Category == category },
+	}
+}
+
+// Bundles ending within endingSoonWindow go into the ending-soon feed.
+// The window is measured from the start of the current endingSoonBucket
+// (UTC-aligned) rather than from the current time, so bundles only cross
+// into the window once per bucket. The bucket does not fully stabilize
+// the feed: expired bundles are dropped at fetch time against the real
+// clock, so a bundle that ends mid-bucket leaves it on the next run.
+const (
+	endingSoonFeedName = "ending-soon"
+	endingSoonWindow   = 48 * time.Hour
+	endingSoonBucket   = 6 * time.Hour
+)
+
+// endingSoonFeed returns the built-in definition of the ending-soon feed
+// for the time bucket containing now.
+func endingSoonFeed(site Site, now time.Time) FeedDefinition {
+	bucket := now.UTC().Truncate(endingSoonBucket)
+	deadline := bucket.Add(endingSoonWindow)
+	return FeedDefinition{
+		Name:        endingSoonFeedName,
+		Title:       fmt.Sprintf(site.locale().endingSoonTitle, site.withDefaults().Name),
+		Description: site.locale().endingSoonDesc,
+		Query:       "end date within 48 hours",
+		// Bundles that already ended never get here; fetchBundles drops them.
+		match: func(b FanaticalBundle) bool { return !b.EndDate.After(deadline) },
+	}
+}
+
+// feedDefinitions returns the built-in category and ending-soon feeds
+// followed by the user-defined ones and the watch feeds, then the same
+// again for every extra site locale, named <name>.<locale>. Names must be
+// unique since they map to file names. The site's metadata overrides are
+// applied to every feed.
+func feedDefinitions(cfg Config, now time.Time) ([]FeedDefinition, error) {
+	defs, err := siteFeedDefinitions(cfg, cfg.Site, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range cfg.Site.Locales {
+		localized, err := siteFeedDefinitions(cfg, cfg.Site.inLocale(code), now)
+		if err != nil {
+			return nil, err
+		}
+		for i := range localized {
+			localized[i].Name += "." + code
+		}
+		defs = append(defs, localized...)
+	}
+
+	seen := map[string]bool{}
+	for _, def := range changesFeeds(cfg.Site) {
+		seen[def.Name] = cfg.ChangesFeed
+	}
+	for i, def := range defs {
+		if seen[def.Name] {
+			return nil, fmt.Errorf("duplicate feed name %q", def.Name)
+		}
+		seen[def.Name] = true
+		def.site.applyFeedMetadata(&defs[i])
+		if err := defs[i].loadTemplates(); err != nil {
+			return nil, err
+		}
+	}
+	for name := range cfg.Site.Feeds {
+		if _, ok := seen[name]; !ok {
+			return nil, fmt.Errorf("site.feeds: unknown feed %q", name)
+		}
+	}
+	return defs, nil
+}
@@ -0,0 +1,86 @@
+package gofanatical
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gofanatical.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOFANATICAL_CONFIG", path)
+}
+
+func TestLoadConfigCompilesFeeds(t *testing.T) {
+	writeConfig(t, `{"feeds": [{"name": "cheap-linux", "query": "price < 5 && \"linux\" in os"}]}`)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if len(cfg.Feeds) != 1 || cfg.Feeds[0].match == nil {
+		t.Fatalf("expected 1 compiled feed, got %+v", cfg.Feeds)
+	}
+	if cfg.Feeds[0].Title == "" || cfg.Feeds[0].Description == "" {
+		t.Error("missing title/description should get defaults")
+	}
+}
+
+func TestLoadConfigRejectsBadFeeds(t *testing.T) {
+	tests := map[string]string{
+		"bad query":    `{"feeds": [{"name": "x", "query": "price <"}]}`,
+		"path in name": `{"feeds": [{"name": "../x", "query": "price < 5"}]}`,
+		"bad json":     `{"feeds": [`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			writeConfig(t, content)
+			if _, err := loadConfig(); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFeedDefinitionsRejectsDuplicateNames(t *testing.T) {
+	for _, name := range []string{"games", "ending-soon"} {
+		cfg := Config{Feeds: []FeedDefinition{{Name: name, Query: "true"}}}
+		if _, err := feedDefinitions(cfg, time.Now()); err == nil {
+			t.Errorf("custom feed shadowing built-in feed %q must be rejected", name)
+		}
+	}
+}
+
+func TestEndingSoonFeedUsesTimeBucket(t *testing.T) {
+	bucket := time.Date(2030, time.March, 1, 6, 0, 0, 0, time.UTC)
+
+	ending := func(d time.Duration) FanaticalBundle {
+		b := testBundle("x", time.Unix(1000, 0))
+		b.EndDate = bucket.Add(d)
+		return b
+	}
+
+	// Every instant in the same 6-hour bucket must select the same bundles.
+	for _, now := range []time.Time{bucket, bucket.Add(3 * time.Hour), bucket.Add(6*time.Hour - time.Second)} {
+		def := endingSoonFeed(defaultSite, now)
+		if !def.match(ending(47 * time.Hour)) {
+			t.Errorf("now=%v: bundle ending in 47h missing", now)
+		}
+		if !def.match(ending(48 * time.Hour)) {
+			t.Errorf("now=%v: bundle ending exactly at the window edge missing", now)
+		}
+		if def.match(ending(49 * time.Hour)) {
+			t.Errorf("now=%v: bundle ending in 49h included", now)
+		}
+	}
+
+	// The next bucket moves the window forward.
+	if !endingSoonFeed(defaultSite, bucket.Add(6*time.Hour)).match(ending(49 * time.Hour)) {
+		t.Error("next bucket did not advance the window")
+	}
+}
@@ -1,64 +1,148 @@
 package gofanatical
 
 import (
+	_ "embed"
 	"fmt"
 	"html"
+	"html/template"
+	"log/slog"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	texttemplate "text/template"
 )
 
-// createRichContent renders the HTML body of a feed item. All dynamic
-// values are HTML-escaped — bundle titles and descriptions come from an
-// external API and must not be trusted as markup.
-func createRichContent(bundle FanaticalBundle) string {
-	var content strings.Builder
-
-	title := html.EscapeString(bundle.Title)
-	description := html.EscapeString(bundle.Description)
-	link := html.EscapeString("https://www.fanatical.com" + bundle.URL)
+//go:embed templates/item.html.tmpl
+var itemContentSource string
 
-	if bundle.Image != "" {
-		content.WriteString(fmt.Sprintf("<img src=\"%s\" alt=\"%s\" style=\"max-width: 100%%; border-radius: 8px; margin-bottom: 10px;\" />\n",
-			html.EscapeString(bundle.Image), title))
-	}
+// itemContentTemplate is the built-in item layout. It escapes every value
+// itself with html.EscapeString, as the feeds always have; user templates
+// go through html/template instead.
+var itemContentTemplate = texttemplate.Must(texttemplate.New("item.html").Funcs(texttemplate.FuncMap{
+	"escape": html.EscapeString,
+}).Parse(itemContentSource))
 
-	content.WriteString(fmt.Sprintf("<h3>%s</h3>\n", title))
-	content.WriteString(fmt.Sprintf("<p>%s</p>\n", description))
+// ItemData is what item templates render. User templates are executed by
+// html/template, which escapes every field for its context, so bundle
+// titles and descriptions — which come from an external API — are never
+// trusted as markup.
+type ItemData struct {
+	Bundle FanaticalBundle
+	// URL is the absolute store link of the bundle.
+	URL string
+	// CurrentPrice, OriginalPrice and Savings carry the currency symbol,
+	// e.g. "$4.99". CurrentPrice is Labels.Free ("FREE") for free
+	// bundles; the others are Labels.NotAvailable ("N/A") when unknown.
+	CurrentPrice  string
+	OriginalPrice string
+	Savings       string
+	// Discount is the discount in percent.
+	Discount int
+	// Ends is the end date in UTC, written the locale's way, e.g.
+	// "March 4, 2030 12:00 UTC" or "4. März 2030 12:00 UTC".
+	Ends  string
+	Flags Flags
+	// Labels are the built-in template's texts in the feed's locale.
+	Labels ItemLabels
+}
 
+func newItemData(bundle FanaticalBundle, site Site) ItemData {
 	symbol := currencySymbol(bundle.Price.Currency)
-
-	currentPrice := fmt.Sprintf("%s%.2f", symbol, bundle.Price.Amount)
+	l := site.locale()
+	data := ItemData{
+		Bundle:        bundle,
+		URL:           site.bundleURL(bundle),
+		CurrentPrice:  fmt.Sprintf("%s%.2f", symbol, bundle.Price.Amount),
+		OriginalPrice: fmt.Sprintf("%s%.2f", symbol, bundle.Price.Original),
+		Savings:       l.labels.NotAvailable,
+		Discount:      bundle.Price.Discount,
+		Ends:          l.formatDate(bundle.EndDate),
+		Flags:         bundle.Flags,
+		Labels:        l.labels,
+	}
 	if bundle.Price.Amount == 0 {
-		currentPrice = "FREE"
+		data.CurrentPrice = l.labels.Free
 	}
-
-	originalPrice := fmt.Sprintf("%s%.2f", symbol, bundle.Price.Original)
 	if bundle.Price.Original == 0 {
-		originalPrice = "N/A"
+		data.OriginalPrice = l.labels.NotAvailable
+	}
+	if savings := bundle.Price.Original - bundle.Price.Amount; savings > 0 {
+		data.Savings = fmt.Sprintf("%s%.2f", symbol, savings)
 	}
+	return data
+}
 
-	savings := bundle.Price.Original - bundle.Price.Amount
-	savingsText := fmt.Sprintf("%s%.2f", symbol, savings)
-	if savings <= 0 {
-		savingsText = "N/A"
+// loadItemTemplate parses a user-supplied item template and renders it
+// once with a sample bundle, so a misspelled field fails the run before
+// anything is written.
+func loadItemTemplate(path string) (*template.Template, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
 	}
+	t, err := template.New(filepath.Base(path)).Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+	sample := FanaticalBundle{Title: "Sample Bundle", Slug: "sample", URL: "/en/bundle/sample",
+		Price: Price{Currency: "USD", Amount: 4.99, Original: 9.99, Discount: 50}}
+	if _, err := renderItem(t, newItemData(sample, defaultSite)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
 
-	content.WriteString("<table border='1' style='border-collapse: collapse; margin: 10px 0;'>\n")
-	content.WriteString("<tr style='background-color: #f0f0f0;'><th style='padding: 5px;'>Current Price</th><th style='padding: 5px;'>Original Price</th><th style='padding: 5px;'>Discount</th><th style='padding: 5px;'>You Save</th></tr>\n")
-	content.WriteString(fmt.Sprintf("<tr><td style='padding: 5px; text-align: center;'><strong>%s</strong></td><td style='padding: 5px; text-align: center;'>%s</td><td style='padding: 5px; text-align: center;'>%d%%</td><td style='padding: 5px; text-align: center;'>%s</td></tr>\n",
-		currentPrice, originalPrice, bundle.Price.Discount, savingsText))
-	content.WriteString("</table>\n")
+// renderItem executes a user-supplied item template.
+func renderItem(t *template.Template, data ItemData) (string, error) {
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
 
-	// No "time remaining" line here: it would be computed from the current
-	// time, making the generated XML differ on every run even when nothing
-	// changed — which would defeat the only-commit-on-real-changes behavior.
-	content.WriteString("<h4>⏰ Availability</h4>\n")
-	content.WriteString("<ul>\n")
-	content.WriteString(fmt.Sprintf("<li><strong>Ends:</strong> %s</li>\n", bundle.EndDate.UTC().Format("January 2, 2006 15:04 MST")))
-	content.WriteString("</ul>\n")
+// renderContent renders the HTML body of a feed item with t, or with the
+// built-in template when t is nil. A template that fails at run time
+// falls back to the built-in one rather than failing the whole feed.
+func renderContent(t *template.Template, bundle FanaticalBundle, site Site) string {
+	if t != nil {
+		content, err := renderItem(t, newItemData(bundle, site))
+		if err == nil {
+			return content
+		}
+		slog.Warn("item template failed, using the built-in one", "template", t.Name(), "bundle", bundle.Slug, "error", err)
+	}
+	return createRichContent(bundle, site)
+}
 
-	content.WriteString(fmt.Sprintf("<p><a href='%s' style='background-color: #ff6f00; color: white; padding: 10px 15px; text-decoration: none; border-radius: 5px;'>🛒 Get this deal on Fanatical</a></p>\n", link))
+// itemContent renders the content of b's item in def.
+func (def FeedDefinition) itemContent(b FanaticalBundle) string {
+	return renderContent(def.content, b, def.site)
+}
 
+// itemDescription renders the description of b's item in def: the
+// bundle description unless def has a description template.
+func (def FeedDefinition) itemDescription(b FanaticalBundle) string {
+	if def.description == nil {
+		return b.Description
+	}
+	description, err := renderItem(def.description, newItemData(b, def.site))
+	if err != nil {
+		slog.Warn("description template failed, using the bundle description", "feed", def.Name, "bundle", b.Slug, "error", err)
+		return b.Description
+	}
+	return description
+}
+
+// createRichContent renders the HTML body of a feed item with the
+// built-in template.
+func createRichContent(bundle FanaticalBundle, site Site) string {
+	var content strings.Builder
+	if err := itemContentTemplate.Execute(&content, newItemData(bundle, site)); err != nil {
+		// The built-in template only uses ItemData fields.
+		panic(err)
+	}
 	return content.String()
 }
 
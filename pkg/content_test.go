@@ -1,11 +1,111 @@
 package gofanatical
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 )
 
+// TestBuiltinItemTemplateOutput pins the built-in item content. Feed
+// readers and the item diff see any change here as an edited item.
+func TestBuiltinItemTemplateOutput(t *testing.T) {
+	bundle := FanaticalBundle{
+		Title:       "Build+Play & 'Friends'",
+		Description: "Ten games",
+		Image:       "https://example.com/cover.jpg?a=1&b=2",
+		URL:         "/en/bundle/build-play",
+		EndDate:     time.Date(2030, time.March, 4, 12, 0, 0, 0, time.UTC),
+		Price:       Price{Currency: "USD", Amount: 4.99, Original: 49.99, Discount: 90},
+	}
+	want := `<img src="https://example.com/cover.jpg?a=1&amp;b=2" alt="Build+Play &amp; &#39;Friends&#39;" style="max-width: 100%; border-radius: 8px; margin-bottom: 10px;" />
+<h3>Build+Play &amp; &#39;Friends&#39;</h3>
+<p>Ten games</p>
+<table border='1' style='border-collapse: collapse; margin: 10px 0;'>
+<tr style='background-color: #f0f0f0;'><th style='padding: 5px;'>Current Price</th><th style='padding: 5px;'>Original Price</th><th style='padding: 5px;'>Discount</th><th style='padding: 5px;'>You Save</th></tr>
+<tr><td style='padding: 5px; text-align: center;'><strong>$4.99</strong></td><td style='padding: 5px; text-align: center;'>$49.99</td><td style='padding: 5px; text-align: center;'>90%</td><td style='padding: 5px; text-align: center;'>$45.00</td></tr>
+</table>
+<h4>⏰ Availability</h4>
+<ul>
+<li><strong>Ends:</strong> March 4, 2030 12:00 UTC</li>
+</ul>
+<p><a href='https://www.fanatical.com/en/bundle/build-play' style='background-color: #ff6f00; color: white; padding: 10px 15px; text-decoration: none; border-radius: 5px;'>🛒 Get this deal on Fanatical</a></p>
+`
+	if got := createRichContent(bundle, defaultSite); got != want {
+		t.Errorf("built-in content changed:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Cover URLs are built from raw API file names and must come out
+	// HTML-escaped only, never URL-encoded.
+	for cover, want := range map[string]string{
+		"https://example.com/original/Cover (1).jpg": `<img src="https://example.com/original/Cover (1).jpg"`,
+		"https://example.com/Pokémon's.jpg":          `<img src="https://example.com/Pokémon&#39;s.jpg"`,
+		"https://example.com/a+b.jpg?w=1&h=2":        `<img src="https://example.com/a+b.jpg?w=1&amp;h=2"`,
+	} {
+		bundle.Image = cover
+		if got := createRichContent(bundle, defaultSite); !strings.HasPrefix(got, want) {
+			t.Errorf("cover %q rendered as:\n%s\nwant prefix %s", cover, got, want)
+		}
+	}
+}
+
+func TestFeedItemTemplates(t *testing.T) {
+	dir := t.TempDir()
+	content := filepath.Join(dir, "terse.html.tmpl")
+	description := filepath.Join(dir, "description.html.tmpl")
+	os.WriteFile(content, []byte(`<b>{{.Bundle.Title}}</b> {{.CurrentPrice}} (-{{.Discount}}%){{if .Flags.StarDeal}} ★{{end}} <a href="{{.URL}}">buy</a>`), 0o644)
+	os.WriteFile(description, []byte(`{{.CurrentPrice}} until {{.Ends}}`), 0o644)
+	writeConfig(t, fmt.Sprintf(`{
+		"feeds": [{"name": "discord", "query": "price < 10", "content_template": %q, "description_template": %q}],
+		"site": {"feeds": {"games": {"content_template": %q}}}
+	}`, content, description, content))
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := feedDefinitions(cfg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := testBundle("deal", time.Unix(1000, 0))
+	bundle.Title = "<Deal>"
+	bundle.Flags.StarDeal = true
+	for _, def := range defs {
+		item := createFeed([]FanaticalBundle{bundle}, def).Items[0]
+		switch def.Name {
+		case "discord":
+			if want := `<b>&lt;Deal&gt;</b> $4.99 (-50%) ★ <a href="https://www.fanatical.com/en/bundle/deal">buy</a>`; item.Content != want {
+				t.Errorf("content = %q, want %q", item.Content, want)
+			}
+			if !strings.HasPrefix(item.Description, "$4.99 until ") {
+				t.Errorf("description = %q", item.Description)
+			}
+		case "games":
+			if !strings.HasPrefix(item.Content, "<b>") || item.Description != bundle.Description {
+				t.Errorf("games item = %q / %q, want the template content and the bundle description", item.Content, item.Description)
+			}
+		case "books":
+			if item.Content != createRichContent(bundle, defaultSite) {
+				t.Errorf("books should keep the built-in content, got %q", item.Content)
+			}
+		}
+	}
+}
+
+func TestLoadItemTemplateRejectsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.html.tmpl")
+	os.WriteFile(path, []byte(`{{.Bundle.Name}}`), 0o644)
+	if _, err := loadItemTemplate(path); err == nil {
+		t.Error("template using a missing field should fail to load")
+	}
+	if _, err := loadItemTemplate(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+		t.Error("missing template file should fail to load")
+	}
+}
+
 func TestCreateRichContentEscapesHTML(t *testing.T) {
 	bundle := FanaticalBundle{
 		Title:       `Evil <script>alert("x")</script> & Friends`,
@@ -16,7 +116,7 @@ func TestCreateRichContentEscapesHTML(t *testing.T) {
 		Price:       Price{Currency: "USD", Amount: 4.99, Original: 9.99, Discount: 50},
 	}
 
-	content := createRichContent(bundle)
+	content := createRichContent(bundle, defaultSite)
 
 	if strings.Contains(content, "<script>") {
 		t.Error("title was not HTML-escaped: raw <script> tag in output")
@@ -41,14 +141,14 @@ func TestCreateRichContentPrices(t *testing.T) {
 
 	free := base
 	free.Price = Price{Currency: "USD", Amount: 0, Original: 0}
-	content := createRichContent(free)
+	content := createRichContent(free, defaultSite)
 	if !strings.Contains(content, "FREE") || !strings.Contains(content, "N/A") {
 		t.Error("free bundle should render FREE and N/A")
 	}
 
 	euro := base
 	euro.Price = Price{Currency: "EUR", Amount: 3.49, Original: 34.99, Discount: 90}
-	content = createRichContent(euro)
+	content = createRichContent(euro, defaultSite)
 	if !strings.Contains(content, "€3.49") || !strings.Contains(content, "€34.99") {
 		t.Errorf("expected euro prices in output, got: %s", content)
 	}
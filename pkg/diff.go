@@ -0,0 +1,122 @@
+package gofanatical
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// FeedDiff lists the items that differ between the published feed and
+// the newly generated one.
+type FeedDiff struct {
+	Added   []DiffItem
+	Removed []DiffItem
+	Changed []DiffItem
+	// Initial is set when there was no published feed to compare with,
+	// so every item counts as added.
+	Initial bool
+}
+
+// DiffItem identifies a feed item.
+type DiffItem struct {
+	GUID  string
+	Title string
+}
+
+// Empty reports whether the two feeds have the same items.
+func (d FeedDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+type rssDocument struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"encoded"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// parseRSSItems returns the items of an RSS document keyed by GUID.
+func parseRSSItems(data []byte) (map[string]rssItem, error) {
+	var doc rssDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	items := make(map[string]rssItem, len(doc.Channel.Items))
+	for _, item := range doc.Channel.Items {
+		items[item.GUID] = item
+	}
+	return items, nil
+}
+
+// diffFeeds compares two rendered RSS documents item by item. A missing
+// or unreadable old document counts as empty, so every item is added and
+// the diff is marked Initial.
+func diffFeeds(oldRSS, newRSS []byte) (FeedDiff, error) {
+	var diff FeedDiff
+
+	newItems, err := parseRSSItems(newRSS)
+	if err != nil {
+		return diff, fmt.Errorf("failed to parse generated RSS: %w", err)
+	}
+	oldItems, err := parseRSSItems(oldRSS)
+	if err != nil {
+		oldItems = nil
+		diff.Initial = true
+	}
+
+	for guid, item := range newItems {
+		old, ok := oldItems[guid]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, DiffItem{GUID: guid, Title: item.Title})
+		case old != item:
+			diff.Changed = append(diff.Changed, DiffItem{GUID: guid, Title: item.Title})
+		}
+	}
+	for guid, item := range oldItems {
+		if _, ok := newItems[guid]; !ok {
+			diff.Removed = append(diff.Removed, DiffItem{GUID: guid, Title: item.Title})
+		}
+	}
+
+	sortDiffItems(diff.Added)
+	sortDiffItems(diff.Removed)
+	sortDiffItems(diff.Changed)
+	return diff, nil
+}
+
+func sortDiffItems(items []DiffItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Title != items[j].Title {
+			return items[i].Title < items[j].Title
+		}
+		return items[i].GUID < items[j].GUID
+	})
+}
+
+// WriteDiffSummary prints, per feed, the items a run added, removed and
+// changed compared with the files that were on disk before it.
+func (r Result) WriteDiffSummary(w io.Writer) {
+	for _, fr := range r.Feeds {
+		fmt.Fprintf(w, "%s: %d added, %d removed, %d changed\n",
+			fr.File, len(fr.Diff.Added), len(fr.Diff.Removed), len(fr.Diff.Changed))
+		for _, item := range fr.Diff.Added {
+			fmt.Fprintf(w, "  + %s\n", item.Title)
+		}
+		for _, item := range fr.Diff.Removed {
+			fmt.Fprintf(w, "  - %s\n", item.Title)
+		}
+		for _, item := range fr.Diff.Changed {
+			fmt.Fprintf(w, "  ~ %s\n", item.Title)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package gofanatical
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffFeeds(t *testing.T) {
+	render := func(bundles ...FanaticalBundle) []byte {
+		feed := createFeed(bundles, categoryFeed(defaultSite, "games"))
+		rss, err := feed.ToRss()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []byte(rss)
+	}
+
+	kept := testBundle("kept", time.Unix(1000, 0))
+	cheaper := kept
+	cheaper.Price.Amount = 1.99
+	gone := testBundle("gone", time.Unix(2000, 0))
+	added := testBundle("added", time.Unix(3000, 0))
+
+	diff, err := diffFeeds(render(kept, gone), render(cheaper, added))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Initial {
+		t.Error("diff against an existing feed marked Initial")
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Title != "Bundle added" {
+		t.Errorf("Added = %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Title != "Bundle gone" {
+		t.Errorf("Removed = %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Title != "Bundle kept" {
+		t.Errorf("Changed = %v", diff.Changed)
+	}
+
+	// No file on disk yet: everything is new.
+	diff, err = diffFeeds(nil, render(kept))
+	if err != nil || len(diff.Added) != 1 || len(diff.Removed) != 0 || !diff.Initial {
+		t.Errorf("diff against missing file = %+v, err %v", diff, err)
+	}
+
+	if diff, _ := diffFeeds(render(kept), render(kept)); !diff.Empty() {
+		t.Errorf("identical feeds produced a diff: %+v", diff)
+	}
+}
@@ -0,0 +1,184 @@
+package gofanatical
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DiscordConfig enables announcing new bundles to a Discord channel.
+type DiscordConfig struct {
+	// WebhookURL is a secret; when empty, DISCORD_WEBHOOK_URL is used.
+	WebhookURL string `json:"webhook_url"`
+	Username   string `json:"username"`
+	AvatarURL  string `json:"avatar_url"`
+}
+
+const (
+	discordEmbedsPerMessage = 10 // Discord rejects more per message
+	discordAttempts         = 5
+	discordColor            = 0xFF6F00 // the "Get this deal" button orange
+)
+
+// DiscordNotifier posts one rich embed per new bundle via a webhook.
+type DiscordNotifier struct {
+	cfg    DiscordConfig
+	site   Site
+	client *http.Client
+	sleep  func(time.Duration)
+}
+
+// NewDiscordNotifier resolves the webhook URL from cfg or the environment.
+func NewDiscordNotifier(cfg DiscordConfig) (*DiscordNotifier, error) {
+	if cfg.WebhookURL == "" {
+		cfg.WebhookURL = os.Getenv("DISCORD_WEBHOOK_URL")
+	}
+	if cfg.WebhookURL == "" {
+		return nil, fmt.Errorf("discord: webhook_url or DISCORD_WEBHOOK_URL is required")
+	}
+	return &DiscordNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		sleep:  time.Sleep,
+	}, nil
+}
+
+// Name implements Notifier.
+func (d *DiscordNotifier) Name() string { return "discord" }
+
+type discordMessage struct {
+	Username  string         `json:"username,omitempty"`
+	AvatarURL string         `json:"avatar_url,omitempty"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color"`
+	Thumbnail   *discordImage       `json:"thumbnail,omitempty"`
+	Fields      []discordEmbedField `json:"fields"`
+	Timestamp   string              `json:"timestamp"`
+	Footer      discordFooter       `json:"footer"`
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// Notify implements Notifier, sending up to ten embeds per message.
+func (d *DiscordNotifier) Notify(events Events) error {
+	for start := 0; start < len(events.New); start += discordEmbedsPerMessage {
+		end := min(start+discordEmbedsPerMessage, len(events.New))
+		msg := discordMessage{Username: d.cfg.Username, AvatarURL: d.cfg.AvatarURL}
+		for _, b := range events.New[start:end] {
+			msg.Embeds = append(msg.Embeds, discordEmbedFor(b, d.site))
+		}
+		if err := d.post(msg); err != nil {
+			return fmt.Errorf("discord: %w", err)
+		}
+	}
+	return nil
+}
+
+func discordEmbedFor(b FanaticalBundle, site Site) discordEmbed {
+	original := "N/A"
+	if b.Price.Original > 0 {
+		original = formatAmount(b.Price.Currency, b.Price.Original)
+	}
+	embed := discordEmbed{
+		Title:       truncateRunes(b.Title, 256),
+		URL:         site.bundleURL(b),
+		Description: truncateRunes(b.Description, 4096),
+		Color:       discordColor,
+		Fields: []discordEmbedField{
+			{Name: "Price", Value: formatAmount(b.Price.Currency, b.Price.Amount), Inline: true},
+			{Name: "Original", Value: original, Inline: true},
+			{Name: "Discount", Value: fmt.Sprintf("%d%%", b.Price.Discount), Inline: true},
+		},
+		// Discord renders the timestamp in the reader's local time zone.
+		Timestamp: b.EndDate.UTC().Format(time.RFC3339),
+		Footer:    discordFooter{Text: "Ends"},
+	}
+	if b.Image != "" {
+		embed.Thumbnail = &discordImage{URL: b.Image}
+	}
+	return embed
+}
+
+// post sends one message, waiting out rate limits: a 429 is retried after
+// the advertised delay, and an exhausted bucket (X-RateLimit-Remaining: 0)
+// delays the next message until it resets.
+func (d *DiscordNotifier) post(msg discordMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := d.client.Post(d.cfg.WebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to post webhook: %w", err)
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+				d.sleep(secondsHeader(resp.Header.Get("X-RateLimit-Reset-After")))
+			}
+			return nil
+		case attempt >= discordAttempts:
+			return fmt.Errorf("webhook returned status %d after %d attempts", resp.StatusCode, attempt)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			var limited struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			wait := secondsHeader(resp.Header.Get("Retry-After"))
+			if json.Unmarshal(respBody, &limited) == nil && limited.RetryAfter > 0 {
+				wait = time.Duration(limited.RetryAfter * float64(time.Second))
+			}
+			slog.Warn("discord rate limited", "retry_after", wait, "attempt", attempt)
+			d.sleep(wait)
+		case resp.StatusCode >= 500:
+			d.sleep(time.Duration(attempt*2) * time.Second)
+		default:
+			return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+		}
+	}
+}
+
+// secondsHeader parses a header holding (fractional) seconds.
+func secondsHeader(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return time.Second
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
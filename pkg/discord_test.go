@@ -0,0 +1,104 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDiscordNotifierPostsEmbedsAndHandlesRateLimit(t *testing.T) {
+	var messages []discordMessage
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"message": "You are being rate limited.", "retry_after": 0.25, "global": false}`)
+			return
+		}
+		var msg discordMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		messages = append(messages, msg)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	n, err := NewDiscordNotifier(DiscordConfig{WebhookURL: server.URL, Username: "Deals"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slept []time.Duration
+	n.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	var events Events
+	for i := 0; i < 12; i++ {
+		b := testBundle(fmt.Sprintf("b%02d", i), time.Unix(1000, 0))
+		b.Image = "https://example.com/cover.jpg"
+		b.EndDate = time.Date(2030, time.March, 1, 18, 0, 0, 0, time.UTC)
+		events.New = append(events.New, b)
+	}
+
+	if err := n.Notify(events); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if len(slept) != 1 || slept[0] != 250*time.Millisecond {
+		t.Errorf("slept %v, want one 250ms wait for the rate limit", slept)
+	}
+	if len(messages) != 2 || len(messages[0].Embeds) != 10 || len(messages[1].Embeds) != 2 {
+		t.Fatalf("expected 2 messages with 10+2 embeds, got %d", len(messages))
+	}
+
+	embed := messages[0].Embeds[0]
+	if embed.Title != "Bundle b00" || embed.URL != "https://www.fanatical.com/en/bundle/b00" {
+		t.Errorf("embed title/url = %q %q", embed.Title, embed.URL)
+	}
+	if embed.Thumbnail == nil || embed.Thumbnail.URL != "https://example.com/cover.jpg" {
+		t.Errorf("embed thumbnail = %+v", embed.Thumbnail)
+	}
+	if embed.Timestamp != "2030-03-01T18:00:00Z" {
+		t.Errorf("embed timestamp = %q, want the end time", embed.Timestamp)
+	}
+	wantFields := []discordEmbedField{
+		{Name: "Price", Value: "$4.99", Inline: true},
+		{Name: "Original", Value: "$9.99", Inline: true},
+		{Name: "Discount", Value: "50%", Inline: true},
+	}
+	for i, f := range wantFields {
+		if embed.Fields[i] != f {
+			t.Errorf("field %d = %+v, want %+v", i, embed.Fields[i], f)
+		}
+	}
+	if messages[0].Username != "Deals" {
+		t.Errorf("username = %q", messages[0].Username)
+	}
+}
+
+func TestDiscordNotifierGivesUpOnClientError(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	n, err := NewDiscordNotifier(DiscordConfig{WebhookURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.sleep = func(time.Duration) {}
+
+	events := Events{New: []FanaticalBundle{testBundle("x", time.Unix(1000, 0))}}
+	if err := n.Notify(events); err == nil {
+		t.Error("expected error for a deleted webhook")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request for a 404, got %d", requests)
+	}
+}
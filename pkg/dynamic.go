@@ -0,0 +1,195 @@
+package gofanatical
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dynamicFeedPath serves feeds filtered by query parameters in server
+// mode. It shadows a configured feed named "feed".
+const dynamicFeedPath = "/feed.rss"
+
+// maxDynamicFeeds bounds the per-snapshot cache. Queries beyond it are
+// rendered on every request.
+const maxDynamicFeeds = 256
+
+// snapshot is the bundle list of the latest successful fetch, with the
+// dynamic feeds rendered from it so far.
+type snapshot struct {
+	bundles []FanaticalBundle
+	at      time.Time
+	site    Site
+
+	mu    sync.Mutex
+	feeds map[string]cachedFile
+}
+
+// dynamicFeedQuery translates the parameters of a /feed.rss request into
+// a query expression. Values of one parameter are alternatives, separate
+// parameters must all match:
+//
+//	category=games,software&max_price=5&os=linux
+//	→ (category == "games" || category == "software") && price <= 5 && ("linux" in os)
+//
+// The expression is normalized (lowercase, sorted, deduplicated, fixed
+// parameter order), so equivalent URLs share one cache entry.
+func dynamicFeedQuery(params url.Values) (string, error) {
+	for name := range params {
+		switch name {
+		case "category", "max_price", "min_price", "min_discount", "os", "drm":
+		default:
+			return "", fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+
+	var clauses []string
+	anyOf := func(name string, clause func(v string) string) error {
+		var values []string
+		for _, raw := range params[name] {
+			for _, v := range strings.Split(raw, ",") {
+				if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+					values = append(values, v)
+				}
+			}
+		}
+		slices.Sort(values)
+		values = slices.Compact(values)
+		if name == "category" {
+			for _, v := range values {
+				if !slices.Contains(categories, v) {
+					return fmt.Errorf("unknown category %q", v)
+				}
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		alternatives := make([]string, len(values))
+		for i, v := range values {
+			alternatives[i] = clause(v)
+		}
+		clauses = append(clauses, "("+strings.Join(alternatives, " || ")+")")
+		return nil
+	}
+	number := func(name, field, op string, max float64) error {
+		raw := params.Get(name)
+		if raw == "" {
+			return nil
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		// NaN fails every comparison, so it must be rejected explicitly.
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 || v > max {
+			return fmt.Errorf("invalid %s %q", name, raw)
+		}
+		clauses = append(clauses, field+" "+op+" "+strconv.FormatFloat(v, 'f', -1, 64))
+		return nil
+	}
+
+	for _, err := range []error{
+		anyOf("category", func(v string) string { return "category == " + quoteQueryString(v) }),
+		number("min_price", "price", ">=", 1e9),
+		number("max_price", "price", "<=", 1e9),
+		number("min_discount", "discount", ">=", 100),
+		anyOf("os", func(v string) string { return quoteQueryString(v) + " in os" }),
+		anyOf("drm", func(v string) string { return quoteQueryString(v) + " in drm" }),
+	} {
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(clauses) == 0 {
+		return "true", nil
+	}
+	return strings.Join(clauses, " && "), nil
+}
+
+// dynamicFeed renders the feed for expr, compiled to match, caching it
+// for the lifetime of the snapshot.
+func (s *snapshot) dynamicFeed(expr string, match query) (cachedFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.feeds[expr]; ok {
+		return f, nil
+	}
+
+	def := FeedDefinition{
+		Name:        "feed",
+		Title:       s.site.withDefaults().Name + ": custom feed",
+		Description: "Fanatical bundles matching: " + expr,
+		Query:       expr,
+		match:       match,
+	}
+	s.site.applyFeedMetadata(&def)
+	var filtered []FanaticalBundle
+	for _, b := range s.bundles {
+		if match(b) {
+			filtered = append(filtered, b)
+		}
+	}
+	feed := createFeed(filtered, def)
+	rss, err := renderRSS(feed, def.Language)
+	if err != nil {
+		return cachedFile{}, err
+	}
+
+	f := newCachedFile([]byte(rss), s.at)
+	if len(s.feeds) < maxDynamicFeeds {
+		s.feeds[expr] = f
+	}
+	return f, nil
+}
+
+func (s *Server) setSnapshot(bundles []FanaticalBundle, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap = &snapshot{bundles: bundles, at: at, site: s.site, feeds: map[string]cachedFile{}}
+}
+
+func (s *Server) snapshot() *snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snap
+}
+
+// serveDynamicFeed answers /feed.rss?... from the latest snapshot.
+func (s *Server) serveDynamicFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	expr, err := dynamicFeedQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	match, err := compileQuery(expr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snap := s.snapshot()
+	if snap == nil {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "no bundles fetched yet", http.StatusServiceUnavailable)
+		return
+	}
+	f, err := snap.dynamicFeed(expr, match)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType(dynamicFeedPath))
+	w.Header().Set("ETag", f.etag)
+	w.Header().Set("Cache-Control", defaultCacheControl)
+	http.ServeContent(w, r, dynamicFeedPath, f.modTime, bytes.NewReader(f.data))
+}
@@ -0,0 +1,120 @@
+package gofanatical
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDynamicFeedQuery(t *testing.T) {
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{"", "true"},
+		{"category=games", `(category == "games")`},
+		{"drm=Steam&os=linux,mac&category=software,games&max_price=5.50&min_discount=075",
+			`(category == "games" || category == "software") && price <= 5.5 && discount >= 75 && ("linux" in os || "mac" in os) && ("steam" in drm)`},
+		{"os=linux&os=LINUX,+windows", `("linux" in os || "windows" in os)`},
+		{"min_price=1&max_price=10", `price >= 1 && price <= 10`},
+		{"drm=a%01&os=a%22b", "(\"a\\\"b\" in os) && (\"a\x01\" in drm)"},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.params)
+		got, err := dynamicFeedQuery(values)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.params, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q:\n got %s\nwant %s", tt.params, got, tt.want)
+		}
+		if _, err := compileQuery(got); err != nil {
+			t.Errorf("%q: generated query does not compile: %v", tt.params, err)
+		}
+	}
+
+	// Values reach the query as written, not as Go escape sequences.
+	values, _ := url.ParseQuery("drm=a%01")
+	expr, _ := dynamicFeedQuery(values)
+	if match, err := compileQuery(expr); err != nil || !match(FanaticalBundle{DRM: []string{"a\x01"}}) {
+		t.Errorf("%s does not match DRM \"a\\x01\" (err %v)", expr, err)
+	}
+
+	for _, bad := range []string{"category=movies", "max_price=cheap", "min_discount=120", "max_price=-1", "sort=price",
+		"max_price=NaN", "min_price=Inf", "min_discount=-inf"} {
+		values, _ := url.ParseQuery(bad)
+		if _, err := dynamicFeedQuery(values); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestServerDynamicFeed(t *testing.T) {
+	s := &Server{}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	if resp, err := http.Get(ts.URL + "/feed.rss?category=games"); err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("before the first fetch: %v %v, want 503", resp, err)
+	}
+
+	linux := testBundle("linux-deal", time.Unix(2000, 0))
+	linux.Category = "games"
+	linux.Price.Amount = 2.99
+	linux.Price.Discount = 80
+	linux.OperatingSystems = []string{"windows", "linux"}
+	linux.DRM = []string{"steam"}
+	windows := testBundle("windows-deal", time.Unix(1000, 0))
+	windows.Category = "games"
+	windows.Price.Discount = 80
+	windows.OperatingSystems = []string{"windows"}
+	windows.DRM = []string{"steam"}
+	book := testBundle("book-deal", time.Unix(3000, 0))
+	book.Category = "books"
+	s.setSnapshot([]FanaticalBundle{linux, windows, book}, time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC))
+
+	resp, err := http.Get(ts.URL + "/feed.rss?category=games&max_price=5&min_discount=75&os=linux&drm=steam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/rss+xml; charset=utf-8" {
+		t.Fatalf("status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "fanatical-linux-deal-2000") {
+		t.Error("feed is missing the matching bundle")
+	}
+	for _, guid := range []string{"fanatical-windows-deal-1000", "fanatical-book-deal-3000"} {
+		if strings.Contains(string(body), guid) {
+			t.Errorf("feed contains non-matching %s", guid)
+		}
+	}
+
+	// The same filter spelled differently is served from the cache and
+	// revalidates against the same ETag.
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/feed.rss?drm=STEAM&os=linux&min_discount=75&max_price=5.0&category=games", nil)
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNotModified {
+		t.Errorf("equivalent query with If-None-Match: status %d, want 304", resp2.StatusCode)
+	}
+	if n := len(s.snapshot().feeds); n != 1 {
+		t.Errorf("cache holds %d entries for one normalized query", n)
+	}
+
+	for _, bad := range []string{"max_price=free", "max_price=NaN"} {
+		if resp, err := http.Get(ts.URL + "/feed.rss?" + bad); err != nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("invalid parameter %s: %v %v, want 400", bad, resp, err)
+		}
+	}
+}
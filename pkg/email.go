@@ -0,0 +1,362 @@
+package gofanatical
+
+import (
+	"bytes"
+	"crypto/tls"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/digest.html.tmpl
+var digestHTMLSource string
+
+//go:embed templates/digest.txt.tmpl
+var digestTextSource string
+
+var (
+	digestHTMLTemplate = template.Must(template.New("digest.html").Parse(digestHTMLSource))
+	digestTextTemplate = texttemplate.Must(texttemplate.New("digest.txt").Funcs(texttemplate.FuncMap{
+		"price": formatAmount,
+	}).Parse(digestTextSource))
+)
+
+// Digest schedules.
+const (
+	digestPerRun = "run"
+	digestDaily  = "daily"
+	digestWeekly = "weekly"
+)
+
+const defaultDigestStateFile = "email-digest.json"
+
+// EmailConfig enables an email digest of new bundles, sent through an
+// SMTP server.
+type EmailConfig struct {
+	Host string `json:"host"`
+	// Port defaults to 587, the submission port.
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	// Schedule is "run" (default: one email per run with new bundles),
+	// "daily" or "weekly".
+	Schedule string `json:"schedule"`
+	// StateFile holds the bundles collected for the next daily or weekly
+	// digest. Empty means defaultDigestStateFile.
+	StateFile string `json:"state_file"`
+	// AllowPlaintext permits sending when the server does not offer
+	// STARTTLS. Only meant for a local relay.
+	AllowPlaintext bool `json:"allow_plaintext"`
+	// ContentTemplate renders each bundle in the HTML part, like a feed's
+	// content_template. Empty means the feed item content.
+	ContentTemplate string `json:"content_template"`
+}
+
+// EmailNotifier renders new bundles into an HTML and plain-text email.
+// The password comes from SMTP_PASSWORD.
+type EmailNotifier struct {
+	cfg       EmailConfig
+	site      Site
+	sender    string // bare address of cfg.From, for MAIL FROM
+	password  string
+	tlsConfig *tls.Config
+	content   *template.Template
+}
+
+// NewEmailNotifier validates cfg and reads the password from the
+// environment.
+func NewEmailNotifier(cfg EmailConfig) (*EmailNotifier, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("email: host, from and to are required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	switch cfg.Schedule {
+	case "":
+		cfg.Schedule = digestPerRun
+	case digestPerRun, digestDaily, digestWeekly:
+	default:
+		return nil, fmt.Errorf("email: unknown schedule %q (want run, daily or weekly)", cfg.Schedule)
+	}
+	if cfg.StateFile == "" {
+		cfg.StateFile = defaultDigestStateFile
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("email: invalid from address %q: %w", cfg.From, err)
+	}
+	n := &EmailNotifier{cfg: cfg, sender: from.Address, password: os.Getenv("SMTP_PASSWORD")}
+	if cfg.Username != "" && n.password == "" {
+		return nil, fmt.Errorf("email: SMTP_PASSWORD must be set when username is configured")
+	}
+	if cfg.ContentTemplate != "" {
+		if n.content, err = loadItemTemplate(cfg.ContentTemplate); err != nil {
+			return nil, fmt.Errorf("email: content template: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Name implements Notifier.
+func (e *EmailNotifier) Name() string { return "email" }
+
+// digestState is the pending part of a daily or weekly digest.
+type digestState struct {
+	// Since is when the first pending bundle was collected; the digest is
+	// sent on the first run in a later day or week.
+	Since   time.Time         `json:"since"`
+	Pending []FanaticalBundle `json:"pending"`
+}
+
+// Notify implements Notifier. Per-run digests are sent right away; daily
+// and weekly ones collect bundles in the state file until the period
+// rolls over. Bundles that ended in the meantime are left out.
+func (e *EmailNotifier) Notify(events Events) error {
+	now := events.At
+	if e.cfg.Schedule == digestPerRun {
+		if len(events.New) == 0 {
+			return nil
+		}
+		return e.send(events.New, now)
+	}
+
+	state, err := e.loadState()
+	if err != nil {
+		return err
+	}
+	for _, b := range events.New {
+		if !slices.ContainsFunc(state.Pending, func(p FanaticalBundle) bool { return bundleGUID(p) == bundleGUID(b) }) {
+			state.Pending = append(state.Pending, b)
+		}
+	}
+	if len(state.Pending) == 0 {
+		return nil
+	}
+	if state.Since.IsZero() {
+		state.Since = now
+	}
+
+	if digestPeriod(e.cfg.Schedule, state.Since) != digestPeriod(e.cfg.Schedule, now) {
+		state.Pending = slices.DeleteFunc(state.Pending, func(b FanaticalBundle) bool { return !b.EndDate.After(now) })
+		if len(state.Pending) > 0 {
+			if err := e.send(state.Pending, now); err != nil {
+				// Keep the state so the next run retries.
+				return errors.Join(err, e.saveState(state))
+			}
+		}
+		state = digestState{}
+	}
+	return e.saveState(state)
+}
+
+// digestPeriod identifies the UTC day or ISO week t falls into.
+func digestPeriod(schedule string, t time.Time) string {
+	t = t.UTC()
+	if schedule == digestWeekly {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format(time.DateOnly)
+}
+
+func (e *EmailNotifier) loadState() (digestState, error) {
+	var state digestState
+	data, err := os.ReadFile(e.cfg.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("email: failed to read digest state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("email: invalid digest state %s: %w", e.cfg.StateFile, err)
+	}
+	return state, nil
+}
+
+func (e *EmailNotifier) saveState(state digestState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("email: failed to encode digest state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.cfg.StateFile), 0o755); err != nil {
+		return fmt.Errorf("email: failed to create state directory: %w", err)
+	}
+	if _, err := writeFileAtomic(e.cfg.StateFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return nil
+}
+
+type digest struct {
+	Subject  string
+	Sections []digestSection
+}
+
+type digestSection struct {
+	Heading string
+	Bundles []digestBundle
+}
+
+type digestBundle struct {
+	Bundle  FanaticalBundle
+	URL     string
+	Content template.HTML
+}
+
+// newDigest groups bundles by category in feed order. content renders
+// each bundle; nil means the built-in item template.
+func newDigest(schedule string, bundles []FanaticalBundle, site Site, content *template.Template) digest {
+	noun := "bundles"
+	if len(bundles) == 1 {
+		noun = "bundle"
+	}
+	prefix := "Fanatical"
+	switch schedule {
+	case digestDaily:
+		prefix = "Fanatical daily digest"
+	case digestWeekly:
+		prefix = "Fanatical weekly digest"
+	}
+	d := digest{Subject: fmt.Sprintf("%s: %d new %s", prefix, len(bundles), noun)}
+
+	for _, category := range categories {
+		section := digestSection{Heading: builtinCards[category].Heading}
+		for _, b := range bundles {
+			if b.Category != category {
+				continue
+			}
+			section.Bundles = append(section.Bundles, digestBundle{
+				Bundle: b,
+				URL:    site.bundleURL(b),
+				// Item templates escape every bundle field themselves.
+				Content: template.HTML(renderContent(content, b, site)),
+			})
+		}
+		if len(section.Bundles) > 0 {
+			d.Sections = append(d.Sections, section)
+		}
+	}
+	return d
+}
+
+// message renders the digest as a multipart/alternative email.
+func (e *EmailNotifier) message(d digest, now time.Time) ([]byte, error) {
+	var text, html bytes.Buffer
+	if err := digestTextTemplate.Execute(&text, d); err != nil {
+		return nil, fmt.Errorf("failed to render text digest: %w", err)
+	}
+	if err := digestHTMLTemplate.Execute(&html, d); err != nil {
+		return nil, fmt.Errorf("failed to render HTML digest: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", d.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// send delivers the digest. STARTTLS is required unless AllowPlaintext
+// is set, and authentication is only attempted over TLS (or to
+// localhost, as net/smtp enforces).
+func (e *EmailNotifier) send(bundles []FanaticalBundle, now time.Time) error {
+	msg, err := e.message(newDigest(e.cfg.Schedule, bundles, e.site, e.content), now)
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+
+	c, err := smtp.Dial(net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port)))
+	if err != nil {
+		return fmt.Errorf("email: failed to connect: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		tlsConfig := e.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: e.cfg.Host}
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("email: STARTTLS failed: %w", err)
+		}
+	} else if !e.cfg.AllowPlaintext {
+		return fmt.Errorf("email: %s does not offer STARTTLS", e.cfg.Host)
+	}
+
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.password, e.cfg.Host)); err != nil {
+			return fmt.Errorf("email: authentication failed: %w", err)
+		}
+	}
+	if err := c.Mail(e.sender); err != nil {
+		return fmt.Errorf("email: MAIL FROM rejected: %w", err)
+	}
+	for _, to := range e.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("email: recipient %s rejected: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("email: DATA rejected: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("email: failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("email: message rejected: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,381 @@
+package gofanatical
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpSink is a minimal local SMTP server that records what it receives.
+// With a TLS config it offers STARTTLS.
+type smtpSink struct {
+	host string
+	port int
+	tls  *tls.Config
+
+	mu       sync.Mutex
+	messages []sinkMessage
+}
+
+type sinkMessage struct {
+	From   string
+	To     []string
+	Auth   string
+	Secure bool
+	Data   []byte
+}
+
+func startSMTPSink(t *testing.T, tlsConfig *tls.Config) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	addr := ln.Addr().(*net.TCPAddr)
+	s := &smtpSink{host: addr.IP.String(), port: addr.Port, tls: tlsConfig}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ready")
+
+	var msg sinkMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-sink")
+			if s.tls != nil && !msg.Secure {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 go ahead")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg.Secure = true
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			msg.Auth = string(creds)
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.From = strings.Trim(strings.TrimPrefix(strings.ToUpper(fields[1]), "FROM:"), "<>")
+			msg.From = strings.ToLower(msg.From)
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.To = append(msg.To, strings.Trim(fields[1][len("TO:"):], "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 send it")
+			msg.Data, _ = tp.ReadDotBytes()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpSink) received() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sinkMessage(nil), s.messages...)
+}
+
+// sinkTLS borrows httptest's self-signed certificate for 127.0.0.1.
+func sinkTLS(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(ts.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	return ts.TLS, &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+// digestParts decodes the text and HTML parts of a digest email.
+func digestParts(t *testing.T, data []byte) (subject, text, html string) {
+	t.Helper()
+	m, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	subject, _ = new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		// NextPart undoes the quoted-printable encoding.
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") {
+			html = string(body)
+		} else {
+			text = string(body)
+		}
+	}
+	return subject, text, html
+}
+
+func categorizedBundle(slug, category string, start time.Time) FanaticalBundle {
+	b := testBundle(slug, start)
+	b.Category = category
+	return b
+}
+
+func TestEmailNotifierSendsPerRunDigestOverSTARTTLS(t *testing.T) {
+	serverTLS, clientTLS := sinkTLS(t)
+	sink := startSMTPSink(t, serverTLS)
+	t.Setenv("SMTP_PASSWORD", "hunter2")
+
+	n, err := NewEmailNotifier(EmailConfig{
+		Host:     sink.host,
+		Port:     sink.port,
+		Username: "bot",
+		From:     "Deals Bot <bot@example.com>",
+		To:       []string{"boss@example.com", "team@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.tlsConfig = clientTLS
+
+	game := categorizedBundle("game", "games", time.Unix(2000, 0))
+	book := categorizedBundle("book", "books", time.Unix(1000, 0))
+	if err := n.Notify(Events{At: time.Unix(5000, 0), New: []FanaticalBundle{game, book}}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	msgs := sink.received()
+	if len(msgs) != 1 {
+		t.Fatalf("sink received %d messages, want 1", len(msgs))
+	}
+	msg := msgs[0]
+	if !msg.Secure || msg.Auth != "\x00bot\x00hunter2" {
+		t.Errorf("secure=%v auth=%q, want STARTTLS and PLAIN auth", msg.Secure, msg.Auth)
+	}
+	if msg.From != "bot@example.com" || strings.Join(msg.To, ",") != "boss@example.com,team@example.com" {
+		t.Errorf("envelope from=%q to=%v", msg.From, msg.To)
+	}
+
+	subject, text, html := digestParts(t, msg.Data)
+	if subject != "Fanatical: 2 new bundles" {
+		t.Errorf("subject = %q", subject)
+	}
+	if strings.Index(text, "Books & eBooks") > strings.Index(text, "Games & Gaming") {
+		t.Errorf("sections not in category order:\n%s", text)
+	}
+	for _, want := range []string{"* Bundle game", "$4.99 (was $9.99, -50%)", "https://www.fanatical.com/en/bundle/book"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part missing %q:\n%s", want, text)
+		}
+	}
+	if !strings.Contains(html, createRichContent(game, defaultSite)) {
+		t.Errorf("HTML part does not embed the feed item content:\n%s", html)
+	}
+	if err := n.Notify(Events{At: time.Unix(6000, 0)}); err != nil || len(sink.received()) != 1 {
+		t.Errorf("run without new bundles: err=%v, %d messages", err, len(sink.received()))
+	}
+}
+
+func TestEmailNotifierRequiresSTARTTLS(t *testing.T) {
+	sink := startSMTPSink(t, nil)
+	cfg := EmailConfig{Host: sink.host, Port: sink.port, From: "bot@example.com", To: []string{"a@example.com"}}
+	events := Events{At: time.Unix(5000, 0), New: []FanaticalBundle{categorizedBundle("x", "games", time.Unix(1000, 0))}}
+
+	n, err := NewEmailNotifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(events); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("expected a STARTTLS error, got %v", err)
+	}
+
+	cfg.AllowPlaintext = true
+	if n, err = NewEmailNotifier(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(events); err != nil {
+		t.Fatalf("Notify with allow_plaintext failed: %v", err)
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Errorf("sink received %d messages, want 1", got)
+	}
+}
+
+func TestEmailNotifierDailyDigest(t *testing.T) {
+	sink := startSMTPSink(t, nil)
+	n, err := NewEmailNotifier(EmailConfig{
+		Host: sink.host, Port: sink.port, AllowPlaintext: true,
+		From: "bot@example.com", To: []string{"a@example.com"},
+		Schedule:  digestDaily,
+		StateFile: filepath.Join(t.TempDir(), "digest.json"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d, hour int) time.Time { return time.Date(2030, time.March, d, hour, 0, 0, 0, time.UTC) }
+	first := categorizedBundle("first", "games", day(1, 9))
+	endsToday := categorizedBundle("short", "games", day(1, 9))
+	endsToday.EndDate = day(1, 23)
+	second := categorizedBundle("second", "software", day(1, 17))
+
+	steps := []Events{
+		{At: day(1, 10), New: []FanaticalBundle{first, endsToday}},
+		{At: day(1, 14)},
+		{At: day(1, 18), New: []FanaticalBundle{second, first}},
+	}
+	for _, events := range steps {
+		if err := n.Notify(events); err != nil {
+			t.Fatalf("Notify at %v failed: %v", events.At, err)
+		}
+	}
+	if got := len(sink.received()); got != 0 {
+		t.Fatalf("sent %d digests before the day was over", got)
+	}
+
+	if err := n.Notify(Events{At: day(2, 6)}); err != nil {
+		t.Fatalf("Notify on the next day failed: %v", err)
+	}
+	msgs := sink.received()
+	if len(msgs) != 1 {
+		t.Fatalf("sink received %d messages, want 1", len(msgs))
+	}
+	subject, text, _ := digestParts(t, msgs[0].Data)
+	if subject != "Fanatical daily digest: 2 new bundles" {
+		t.Errorf("subject = %q", subject)
+	}
+	if strings.Contains(text, "Bundle short") {
+		t.Error("digest includes a bundle that already ended")
+	}
+	if strings.Count(text, "* Bundle first") != 1 || !strings.Contains(text, "* Bundle second") {
+		t.Errorf("digest should list first and second once:\n%s", text)
+	}
+
+	if err := n.Notify(Events{At: day(3, 6)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sink.received()); got != 1 {
+		t.Errorf("digest was sent again after the state was cleared (%d messages)", got)
+	}
+}
+
+func TestEmailNotifierKeepsDigestStateWhenSendFails(t *testing.T) {
+	// Nothing listens on a port that was just closed.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	stateFile := filepath.Join(t.TempDir(), "digest.json")
+	n, err := NewEmailNotifier(EmailConfig{
+		Host: "127.0.0.1", Port: port, From: "bot@example.com", To: []string{"a@example.com"},
+		Schedule: digestDaily, StateFile: stateFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d, hour int) time.Time { return time.Date(2030, time.March, d, hour, 0, 0, 0, time.UTC) }
+	live := categorizedBundle("live", "games", day(1, 9))
+	ended := categorizedBundle("ended", "games", day(1, 9))
+	ended.EndDate = day(1, 23)
+	if err := n.Notify(Events{At: day(1, 10), New: []FanaticalBundle{ended, live}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(Events{At: day(2, 6)}); err == nil {
+		t.Fatal("Notify succeeded without an SMTP server")
+	}
+
+	state, err := n.loadState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Pending) != 1 || state.Pending[0].Slug != "live" {
+		t.Errorf("pending after a failed send = %+v, want only the live bundle", state.Pending)
+	}
+}
+
+func TestDigestPeriod(t *testing.T) {
+	sunday := time.Date(2030, time.March, 3, 23, 0, 0, 0, time.UTC)
+	monday := sunday.Add(2 * time.Hour)
+	if digestPeriod(digestWeekly, sunday) == digestPeriod(digestWeekly, monday) {
+		t.Error("Sunday and Monday fell into the same ISO week")
+	}
+	if got := digestPeriod(digestDaily, sunday); got != "2030-03-03" {
+		t.Errorf("daily period = %q", got)
+	}
+}
+
+func TestEmailNotifierContentTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rich.html.tmpl")
+	if err := os.WriteFile(path, []byte(`<div class="deal">{{.Bundle.Title}} for {{.CurrentPrice}}</div>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewEmailNotifier(EmailConfig{Host: "localhost", From: "bot@example.com", To: []string{"a@example.com"}, ContentTemplate: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDigest(digestPerRun, []FanaticalBundle{categorizedBundle("x", "games", time.Unix(1000, 0))}, defaultSite, n.content)
+	if got := string(d.Sections[0].Bundles[0].Content); got != `<div class="deal">Bundle x for $4.99</div>` {
+		t.Errorf("content = %q", got)
+	}
+
+	if _, err := NewEmailNotifier(EmailConfig{Host: "localhost", From: "bot@example.com", To: []string{"a@example.com"},
+		ContentTemplate: filepath.Join(t.TempDir(), "missing.tmpl")}); err == nil {
+		t.Error("missing content template should be rejected")
+	}
+}
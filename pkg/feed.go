@@ -1,55 +1,276 @@
 package gofanatical
 
 import (
-	"bufio"
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gorilla/feeds"
 )
 
 var categories = []string{"books", "games", "software"}
 
-// Run fetches all bundles once, then writes one RSS feed per category.
-// It returns a non-nil error if fetching fails or any feed cannot be
-// written, so the caller can exit non-zero and CI turns red instead of
-// silently serving stale feeds.
-func Run() error {
+// Clock reports the current time. Tests substitute a fixed instant.
+type Clock func() time.Time
+
+// Options configures a Run. The zero value is the production setup.
+type Options struct {
+	// Clock is read once per run; every time-dependent step (expiry
+	// filtering, time buckets, history timestamps) uses that instant.
+	// Nil means time.Now.
+	Clock Clock
+	// Publisher receives the generated files. Nil means the S3 store from
+	// the config file if one is configured, otherwise a LocalPublisher
+	// writing to OutDir.
+	Publisher Publisher
+	// OutDir is where feeds are written by the default LocalPublisher.
+	// Empty means "docs".
+	OutDir string
+	// DryRun generates everything but writes nothing — neither feeds nor
+	// the history log. Each FeedResult still carries the diff against the
+	// file currently on disk.
+	DryRun bool
+	// MetricsFile, if set, receives the Prometheus metrics after the run,
+	// for node_exporter's textfile collector. Dry runs skip it.
+	MetricsFile string
+}
+
+func (o Options) now() time.Time {
+	if o.Clock == nil {
+		return time.Now()
+	}
+	return o.Clock()
+}
+
+func (o Options) outDir() string {
+	if o.OutDir == "" {
+		return "docs"
+	}
+	return o.OutDir
+}
+
+// Result summarizes a Run.
+type Result struct {
+	Feeds []FeedResult
+	// Bundles is the deduplicated snapshot the feeds were built from. It
+	// is nil if fetching failed.
+	Bundles []FanaticalBundle
+	// FetchDuration is how long fetching took, retries included.
+	FetchDuration time.Duration
+}
+
+// FeedResult reports what happened to one output feed.
+type FeedResult struct {
+	Name  string
+	File  string
+	Items int
+	Size  int
+	// GzipSize is the size of the .rss.gz sibling, 0 when not generated.
+	GzipSize int
+	// Dropped counts the items cut by the feed's limits.
+	Dropped int
+	// Changed is false when the file already had identical content and
+	// was left untouched. In a dry run it reports whether the file would
+	// have been written.
+	Changed bool
+	Diff    FeedDiff
+}
+
+// Run fetches all bundles once and writes one RSS feed per category, the
+// ending-soon feed, one feed per user-defined feed and watchlist, the
+// index.html listing them, and status.json describing the run. It returns
+// a non-nil error if fetching fails or any feed cannot be written, so the
+// caller can exit non-zero and CI turns red instead of silently serving
+// stale feeds. The result lists every feed that was written.
+func Run(opts Options) (Result, error) {
+	result, err := run(opts)
+
+	metricLastRun.Set(float64(opts.now().Unix()))
+	metricLastRunSuccess.Set(0)
+	if err == nil {
+		metricLastRunSuccess.Set(1)
+	}
+	if opts.MetricsFile != "" && !opts.DryRun {
+		err = errors.Join(err, writeMetricsFile(opts.MetricsFile))
+	}
+	return result, err
+}
+
+// run is Run without the metrics bookkeeping.
+func run(opts Options) (result Result, err error) {
 	configureLogging()
+	now := opts.now()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return result, err
+	}
+	defs, err := feedDefinitions(cfg, now)
+	if err != nil {
+		return result, err
+	}
+	changesDefs := changesFeeds(cfg.Site)
+	for i := range changesDefs {
+		if err := changesDefs[i].loadTemplates(); err != nil {
+			return result, err
+		}
+	}
 
-	bundles, err := fetchBundles()
+	publisher, err := resolvePublisher(opts, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to fetch bundles: %w", err)
+		return result, err
+	}
+	if !opts.DryRun {
+		// Failed runs are reported too; that is the point of the file.
+		defer func() {
+			err = errors.Join(err, publishStatus(publisher, result, err == nil, now))
+		}()
+	}
+
+	notifiers, err := configuredNotifiers(cfg)
+	if err != nil {
+		return result, err
+	}
+	watchers, err := watchlistNotifiers(cfg)
+	if err != nil {
+		return result, err
+	}
+
+	fetchStart := time.Now()
+	bundles, err := fetchBundles(now)
+	result.FetchDuration = time.Since(fetchStart)
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch bundles: %w", err)
 	}
 
 	bundles = removeDuplicateBundles(bundles)
+	result.Bundles = bundles
+
+	out := feedOutput{publisher: publisher, dryRun: opts.DryRun, gzip: cfg.Gzip}
+	// Feeds removed from the config must not linger in the metrics.
+	metricFeedItems.Reset()
+	metricFeedBytes.Reset()
 
 	var errs []error
-	for _, category := range categories {
+	var index []indexFeed
+	// publish applies the feed's limits and publishes it. bundles, if
+	// given, are the bundles behind the feed's items.
+	publish := func(feed feeds.Feed, def FeedDefinition, bundles []FanaticalBundle) {
+		name := def.Name
+		dropped, err := applyLimits(&feed, cfg.limitsFor(name), now, def.Language)
+		if err != nil {
+			metricWriteFailures.Inc(name)
+			errs = append(errs, fmt.Errorf("feed %s: %w", name, err))
+			return
+		}
+		if bundles != nil {
+			bundles = keptBundles(bundles, feed.Items)
+		}
+
+		fr, err := out.publish(feed, name, def.Language)
+		if err != nil {
+			metricWriteFailures.Inc(name)
+			errs = append(errs, fmt.Errorf("feed %s: %w", name, err))
+			return
+		}
+		fr.Dropped = dropped
+		metricFeedItems.Set(float64(fr.Items), name)
+		metricFeedBytes.Set(float64(fr.Size), name)
+		result.Feeds = append(result.Feeds, fr)
+		index = append(index, newIndexFeed(name, feed.Title, feed.Description, fr.Items, bundles, out.gzip))
+		slog.Info("successfully created RSS feed", "feed", name, "items", fr.Items, "changed", fr.Changed,
+			"size", fr.Size, "gzip_size", fr.GzipSize)
+	}
+
+	var history *History
+	if cfg.HistoryFile != "" {
+		if history, err = updateHistory(cfg.HistoryFile, bundles, now, opts.DryRun); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// matched holds every bundle of each feed, before limits, for the
+	// notifier events.
+	matched := make(map[string][]FanaticalBundle, len(defs))
+	for _, def := range defs {
 		var filtered []FanaticalBundle
 		for _, bundle := range bundles {
-			if bundle.Category == category {
+			if def.match(bundle) {
 				filtered = append(filtered, bundle)
 			}
 		}
 
 		if len(filtered) == 0 {
-			slog.Warn("no bundles found for category, creating empty feed", "category", category)
+			slog.Warn("no bundles matched feed, creating empty feed", "feed", def.Name)
 		}
 
-		feed := createFeed(filtered, category)
-		if err := writeFeedToFile(feed, category); err != nil {
-			errs = append(errs, fmt.Errorf("category %s: %w", category, err))
-			continue
+		matched[def.Name] = filtered
+		publish(createFeed(filtered, def), def, filtered)
+	}
+
+	if history != nil && cfg.ChangesFeed {
+		changes := collectChanges(history, bundles)
+		for _, def := range changesDefs {
+			publish(createChangesFeed(changes, def), def, nil)
+		}
+	}
+
+	if err := out.publishIndex(index); err != nil {
+		metricWriteFailures.Inc("index")
+		errs = append(errs, err)
+	}
+
+	// Notifiers run even without events, so scheduled digests go out on
+	// time.
+	events := collectEvents(result.Feeds, matched, bundles, history, now)
+	if opts.DryRun {
+		if !events.Empty() {
+			slog.Info("dry run, skipping notifications",
+				"new", len(events.New), "changed", len(events.Changed), "removed", len(events.Removed))
 		}
-		slog.Info("successfully created RSS feed", "category", category, "bundles", len(filtered))
+	} else {
+		errs = append(errs, notifyAll(notifiers, events)...)
+		for _, w := range watchers {
+			watchEvents := collectFeedEvents(result.Feeds, []string{w.feed}, matched, bundles, history, now)
+			errs = append(errs, notifyAll(w.notifiers, watchEvents)...)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// resolvePublisher picks opts.Publisher, else the S3 store from the
+// config, else the output directory.
+func resolvePublisher(opts Options, cfg Config) (Publisher, error) {
+	switch {
+	case opts.Publisher != nil:
+		return opts.Publisher, nil
+	case cfg.S3 != nil:
+		return NewS3Publisher(*cfg.S3)
+	default:
+		return LocalPublisher{Dir: opts.outDir()}, nil
 	}
+}
 
-	return errors.Join(errs...)
+// updateHistory records the current snapshot in the history store. With
+// readOnly the snapshot is only applied in memory.
+func updateHistory(path string, bundles []FanaticalBundle, now time.Time, readOnly bool) (*History, error) {
+	history, err := openHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	history.readOnly = readOnly
+	if _, err := history.Observe(bundles, now); err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
 func configureLogging() {
@@ -60,34 +281,20 @@ func configureLogging() {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
 }
 
-func createFeed(bundles []FanaticalBundle, category string) feeds.Feed {
-	feed := feeds.Feed{
-		Title:       fmt.Sprintf("Fanatical RSS %s Bundles", strings.ToUpper(category[:1])+category[1:]),
-		Link:        &feeds.Link{Href: "https://feuerlord2.github.io/Fanatical-RSS-Site/"},
-		Description: fmt.Sprintf("Latest Fanatical %s bundles with amazing deals and discounts!", category),
-		Author:      &feeds.Author{Name: "Daniel Winter", Email: "DanielWinterEmsdetten+rss@gmail.com"},
-	}
+func createFeed(bundles []FanaticalBundle, def FeedDefinition) feeds.Feed {
+	feed := def.channel()
 
-	// Newest bundles first; tie-break on slug so the output order (and thus
-	// the generated XML) is deterministic across runs.
-	sort.Slice(bundles, func(i, j int) bool {
-		if !bundles[i].StartDate.Equal(bundles[j].StartDate) {
-			return bundles[i].StartDate.After(bundles[j].StartDate)
-		}
-		return bundles[i].Slug < bundles[j].Slug
-	})
+	sortBundlesNewestFirst(bundles)
 
 	feed.Items = make([]*feeds.Item, len(bundles))
 	for idx, bundle := range bundles {
 		item := &feeds.Item{
 			Title:       bundle.Title,
-			Link:        &feeds.Link{Href: "https://www.fanatical.com" + bundle.URL},
-			Content:     createRichContent(bundle),
+			Link:        &feeds.Link{Href: def.site.bundleURL(bundle)},
+			Content:     def.itemContent(bundle),
 			Created:     bundle.StartDate,
-			Description: bundle.Description,
-			// The GUID format must stay stable across releases — changing it
-			// makes every feed reader re-deliver all items as new.
-			Id: fmt.Sprintf("fanatical-%s-%d", bundle.Slug, bundle.StartDate.Unix()),
+			Description: def.itemDescription(bundle),
+			Id:          bundleGUID(bundle),
 		}
 
 		// Cover image as enclosure for Discord embed support.
@@ -112,6 +319,25 @@ func createFeed(bundles []FanaticalBundle, category string) feeds.Feed {
 	return feed
 }
 
+// sortBundlesNewestFirst orders bundles like the feed items. Ties break
+// on slug so the output order (and thus the generated XML) is
+// deterministic across runs.
+func sortBundlesNewestFirst(bundles []FanaticalBundle) {
+	sort.Slice(bundles, func(i, j int) bool {
+		if !bundles[i].StartDate.Equal(bundles[j].StartDate) {
+			return bundles[i].StartDate.After(bundles[j].StartDate)
+		}
+		return bundles[i].Slug < bundles[j].Slug
+	})
+}
+
+// bundleGUID returns the feed item GUID of a bundle. The format must stay
+// stable across releases — changing it makes every feed reader re-deliver
+// all items as new.
+func bundleGUID(bundle FanaticalBundle) string {
+	return fmt.Sprintf("fanatical-%s-%d", bundle.Slug, bundle.StartDate.Unix())
+}
+
 func removeDuplicateBundles(bundles []FanaticalBundle) []FanaticalBundle {
 	seen := make(map[string]bool)
 	var unique []FanaticalBundle
@@ -133,34 +359,136 @@ func removeDuplicateBundles(bundles []FanaticalBundle) []FanaticalBundle {
 	return unique
 }
 
-func writeFeedToFile(feed feeds.Feed, category string) error {
-	if err := os.MkdirAll("docs", 0o755); err != nil {
-		return fmt.Errorf("failed to create docs directory: %w", err)
-	}
+// feedOutput publishes rendered feeds.
+type feedOutput struct {
+	publisher Publisher
+	// dryRun publishes nothing; results still report what would change.
+	dryRun bool
+	// gzip also publishes a precompressed <name>.rss.gz sibling.
+	gzip bool
+}
+
+// publish renders feed and hands it to the publisher as <name>.rss.
+// Publishers skip content that is already up to date.
+func (o feedOutput) publish(feed feeds.Feed, name, language string) (FeedResult, error) {
+	filename := name + ".rss"
+	fr := FeedResult{Name: name, File: o.publisher.Location(filename), Items: len(feed.Items)}
 
-	filename := fmt.Sprintf("docs/%s.rss", category)
-	f, err := os.Create(filename)
+	rss, err := renderRSS(feed, language)
 	if err != nil {
-		return fmt.Errorf("failed to create RSS file %s: %w", filename, err)
+		return fr, err
 	}
-	defer f.Close()
+	fr.Size = len(rss)
 
-	rss, err := feed.ToRss()
+	existing, err := o.publisher.Fetch(filename)
 	if err != nil {
-		return fmt.Errorf("failed to generate RSS content: %w", err)
+		return fr, err
+	}
+	if fr.Diff, err = diffFeeds(existing, []byte(rss)); err != nil {
+		return fr, err
 	}
 
-	w := bufio.NewWriter(f)
-	if _, err := w.WriteString(rss); err != nil {
-		return fmt.Errorf("failed to write RSS content: %w", err)
+	var gz []byte
+	if o.gzip {
+		if gz, err = gzipBytes([]byte(rss)); err != nil {
+			return fr, err
+		}
+		fr.GzipSize = len(gz)
 	}
-	if err := w.Flush(); err != nil {
-		return fmt.Errorf("failed to flush RSS file: %w", err)
+
+	if o.dryRun {
+		fr.Changed = !bytes.Equal(existing, []byte(rss))
+		return fr, nil
 	}
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close RSS file: %w", err)
+
+	if fr.Changed, err = o.publisher.Publish(filename, []byte(rss)); err != nil {
+		return fr, err
 	}
+	// The sibling is published even when the feed itself is unchanged, so
+	// enabling gzip later (or a lost .gz file) heals on the next run; the
+	// publisher skips it when it is already up to date.
+	if gz != nil {
+		if _, err := o.publisher.Publish(filename+".gz", gz); err != nil {
+			return fr, err
+		}
+	}
+
+	if fr.Changed {
+		slog.Info("RSS feed written", "feed", name, "file", fr.File, "size", fr.Size)
+	} else {
+		slog.Debug("RSS feed unchanged, skipping write", "feed", name, "file", fr.File)
+	}
+	return fr, nil
+}
 
-	slog.Info("RSS feed written", "category", category, "file", filename, "size", len(rss))
+// publishIndex renders and publishes the landing page listing feeds.
+func (o feedOutput) publishIndex(feeds []indexFeed) error {
+	page, err := renderIndex(feeds)
+	if err != nil {
+		return err
+	}
+	if o.dryRun {
+		return nil
+	}
+	changed, err := o.publisher.Publish("index.html", page)
+	if err != nil {
+		return fmt.Errorf("failed to publish index.html: %w", err)
+	}
+	slog.Info("landing page published", "file", o.publisher.Location("index.html"), "feeds", len(feeds), "changed", changed)
 	return nil
 }
+
+// gzipBytes compresses data deterministically: no file name and a zero
+// modification time in the header, so identical input always yields
+// byte-identical output and unchanged .gz files are never rewritten.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic replaces filename with data unless it already holds
+// exactly data, in which case it reports false and touches nothing. The
+// new content goes to a temp file in the same directory, is fsynced and
+// then renamed over the old file, so a crash or a full disk never leaves
+// a truncated feed for GitHub Pages to serve.
+func writeFileAtomic(filename string, data []byte) (bool, error) {
+	if existing, err := os.ReadFile(filename); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temp file for %s: %w", filename, err)
+	}
+	// Best-effort cleanup; after a successful rename the temp name is gone.
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(data); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		return false, fmt.Errorf("failed to set permissions on %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return false, fmt.Errorf("failed to sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return false, fmt.Errorf("failed to replace %s: %w", filename, err)
+	}
+
+	return true, nil
+}
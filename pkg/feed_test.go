@@ -1,6 +1,11 @@
 package gofanatical
 
 import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -36,7 +41,7 @@ func TestCreateFeedSortsNewestFirst(t *testing.T) {
 	old := testBundle("old", time.Unix(1000, 0))
 	newer := testBundle("newer", time.Unix(2000, 0))
 
-	feed := createFeed([]FanaticalBundle{old, newer}, "games")
+	feed := createFeed([]FanaticalBundle{old, newer}, categoryFeed(defaultSite, "games"))
 
 	if len(feed.Items) != 2 {
 		t.Fatalf("expected 2 items, got %d", len(feed.Items))
@@ -58,12 +63,12 @@ func TestCreateFeedDeterministicOutput(t *testing.T) {
 
 	// Same bundles, different input order (e.g. Algolia re-ranking) must
 	// produce byte-identical RSS, otherwise CI commits phantom changes.
-	feed1 := createFeed(makeBundles([]string{"zeta", "alpha", "mid"}), "games")
+	feed1 := createFeed(makeBundles([]string{"zeta", "alpha", "mid"}), categoryFeed(defaultSite, "games"))
 	rss1, err := feed1.ToRss()
 	if err != nil {
 		t.Fatal(err)
 	}
-	feed2 := createFeed(makeBundles([]string{"mid", "zeta", "alpha"}), "games")
+	feed2 := createFeed(makeBundles([]string{"mid", "zeta", "alpha"}), categoryFeed(defaultSite, "games"))
 	rss2, err := feed2.ToRss()
 	if err != nil {
 		t.Fatal(err)
@@ -78,7 +83,7 @@ func TestCreateFeedStableTimestamp(t *testing.T) {
 	feed := createFeed([]FanaticalBundle{
 		testBundle("a", time.Unix(1000, 0)),
 		testBundle("b", newest),
-	}, "games")
+	}, categoryFeed(defaultSite, "games"))
 
 	// The feed timestamp must derive from content, not from time.Now(),
 	// so unchanged content produces byte-identical XML across runs.
@@ -89,7 +94,7 @@ func TestCreateFeedStableTimestamp(t *testing.T) {
 
 func TestCreateFeedGUIDStability(t *testing.T) {
 	start := time.Unix(1234, 0)
-	feed := createFeed([]FanaticalBundle{testBundle("my-slug", start)}, "games")
+	feed := createFeed([]FanaticalBundle{testBundle("my-slug", start)}, categoryFeed(defaultSite, "games"))
 
 	// This exact GUID format is what existing subscribers' readers have
 	// stored. Never change it, or every item re-delivers as new.
@@ -102,7 +107,7 @@ func TestCreateFeedGUIDStability(t *testing.T) {
 func TestCreateFeedRendersValidRSS(t *testing.T) {
 	bundle := testBundle("render-me", time.Unix(1000, 0))
 	bundle.Image = "https://example.com/cover.png"
-	feed := createFeed([]FanaticalBundle{bundle}, "software")
+	feed := createFeed([]FanaticalBundle{bundle}, categoryFeed(defaultSite, "software"))
 
 	rss, err := feed.ToRss()
 	if err != nil {
@@ -116,8 +121,84 @@ func TestCreateFeedRendersValidRSS(t *testing.T) {
 }
 
 func TestCreateFeedEmptyCategory(t *testing.T) {
-	feed := createFeed(nil, "books")
+	feed := createFeed(nil, categoryFeed(defaultSite, "books"))
 	if _, err := feed.ToRss(); err != nil {
 		t.Fatalf("empty feed must still render: %v", err)
 	}
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "games.rss")
+
+	changed, err := writeFileAtomic(path, []byte("one"))
+	if err != nil || !changed {
+		t.Fatalf("first write: changed=%v err=%v", changed, err)
+	}
+
+	// Identical content must not touch the file at all.
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	changed, err = writeFileAtomic(path, []byte("one"))
+	if err != nil || changed {
+		t.Fatalf("identical write: changed=%v err=%v", changed, err)
+	}
+	if info, _ := os.Stat(path); !info.ModTime().Equal(old) {
+		t.Error("identical write modified the file")
+	}
+
+	changed, err = writeFileAtomic(path, []byte("two"))
+	if err != nil || !changed {
+		t.Fatalf("changed write: changed=%v err=%v", changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two" {
+		t.Errorf("content = %q, want %q", data, "two")
+	}
+
+	// No temp files may be left behind next to the feed.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the feed file in %s, found %d entries", dir, len(entries))
+	}
+}
+
+func TestFeedOutputPublishesDeterministicGzip(t *testing.T) {
+	dir := t.TempDir()
+	out := feedOutput{publisher: LocalPublisher{Dir: dir}, gzip: true}
+	feed := createFeed([]FanaticalBundle{testBundle("gz", time.Unix(1000, 0))}, categoryFeed(defaultSite, "games"))
+
+	fr, err := out.publish(feed, "games", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzPath := filepath.Join(dir, "games.rss.gz")
+	first, err := os.ReadFile(gzPath)
+	if err != nil {
+		t.Fatalf("missing gzip sibling: %v", err)
+	}
+	if fr.GzipSize != len(first) || fr.GzipSize == 0 || fr.GzipSize >= fr.Size {
+		t.Errorf("GzipSize = %d, file %d bytes, feed %d bytes", fr.GzipSize, len(first), fr.Size)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rss, _ := os.ReadFile(filepath.Join(dir, "games.rss"))
+	if !bytes.Equal(plain, rss) {
+		t.Error("gzip sibling does not decompress to the feed")
+	}
+
+	if _, err := out.publish(feed, "games", ""); err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := os.ReadFile(gzPath); !bytes.Equal(first, second) {
+		t.Error("gzip output changed for identical input")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -42,11 +43,13 @@ type AlgoliaBundle struct {
 // errPermanent marks failures that will not change on retry (HTTP 4xx).
 var errPermanent = errors.New("permanent fetch error")
 
-// fetchBundles downloads the current bundle list, retrying transient failures.
-func fetchBundles() ([]FanaticalBundle, error) {
+// fetchBundles downloads the current bundle list, retrying transient
+// failures. Bundles that expired before now are dropped.
+func fetchBundles(now time.Time) ([]FanaticalBundle, error) {
 	var lastErr error
 	for attempt := 1; attempt <= fetchAttempts; attempt++ {
-		bundles, err := fetchBundlesOnce()
+		metricFetchAttempts.Inc()
+		bundles, err := fetchBundlesOnce(now)
 		if err == nil {
 			return bundles, nil
 		}
@@ -62,7 +65,7 @@ func fetchBundles() ([]FanaticalBundle, error) {
 	return nil, lastErr
 }
 
-func fetchBundlesOnce() ([]FanaticalBundle, error) {
+func fetchBundlesOnce(now time.Time) ([]FanaticalBundle, error) {
 	client := &http.Client{Timeout: 30 * time.Second}
 
 	req, err := http.NewRequest(http.MethodGet, bundlesURL, nil)
@@ -75,11 +78,15 @@ func fetchBundlesOnce() ([]FanaticalBundle, error) {
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	req.Header.Set("Referer", "https://www.fanatical.com/en/bundles")
 
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		metricFetchResponses.Inc("error")
 		return nil, fmt.Errorf("failed to fetch Algolia API: %w", err)
 	}
 	defer resp.Body.Close()
+	defer func() { metricFetchDuration.Observe(time.Since(start).Seconds()) }()
+	metricFetchResponses.Inc(strconv.Itoa(resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		err := fmt.Errorf("Algolia API returned status %d", resp.StatusCode)
@@ -96,7 +103,7 @@ func fetchBundlesOnce() ([]FanaticalBundle, error) {
 
 	slog.Info("fetched bundles from Algolia API", "bundles", len(algoliaBundles))
 
-	return convertAlgoliaBundles(algoliaBundles, time.Now()), nil
+	return convertAlgoliaBundles(algoliaBundles, now), nil
 }
 
 // convertAlgoliaBundles turns API bundles into internal ones, dropping
@@ -106,7 +113,17 @@ func convertAlgoliaBundles(algoliaBundles []AlgoliaBundle, now time.Time) []Fana
 	skipped := 0
 
 	for _, ab := range algoliaBundles {
-		if ab.Name == "" || !ab.OnSale || now.Unix() > ab.ValidUntil {
+		var reason string
+		switch {
+		case ab.Name == "":
+			reason = "unnamed"
+		case !ab.OnSale:
+			reason = "not_on_sale"
+		case now.Unix() > ab.ValidUntil:
+			reason = "expired"
+		}
+		if reason != "" {
+			metricBundlesSkipped.Inc(reason)
 			skipped++
 			continue
 		}
@@ -136,9 +153,19 @@ func convertAlgoliaBundles(algoliaBundles []AlgoliaBundle, now time.Time) []Fana
 				Original: originalPrice,
 				Discount: discount,
 			},
+			OperatingSystems: ab.OperatingSystems,
+			DRM:              ab.DRM,
+			Flags: Flags{
+				BestEver:  ab.BestEver,
+				FlashSale: ab.FlashSale,
+				StarDeal:  ab.StarDeal,
+				Giveaway:  ab.Giveaway,
+			},
 		})
 	}
 
+	metricBundlesFetched.Add(float64(len(algoliaBundles)))
+	metricBundlesKept.Add(float64(len(bundles)))
 	slog.Info("bundle conversion completed", "total", len(algoliaBundles), "active", len(bundles), "skipped", skipped)
 
 	return bundles
@@ -135,7 +135,8 @@ func TestCoverImageURL(t *testing.T) {
 }
 
 func TestFetchBundlesOnceAgainstStubServer(t *testing.T) {
-	future := time.Now().Add(72 * time.Hour).Unix()
+	now := time.Unix(1_800_000_000, 0)
+	future := now.Add(72 * time.Hour).Unix()
 	body := fmt.Sprintf(`[
 		{
 			"name": "Killer Bundle 42",
@@ -174,7 +175,7 @@ func TestFetchBundlesOnceAgainstStubServer(t *testing.T) {
 	bundlesURL = server.URL
 	defer func() { bundlesURL = oldURL }()
 
-	bundles, err := fetchBundlesOnce()
+	bundles, err := fetchBundlesOnce(now)
 	if err != nil {
 		t.Fatalf("fetchBundlesOnce failed: %v", err)
 	}
@@ -201,7 +202,7 @@ func TestFetchBundlesNoRetryOnClientError(t *testing.T) {
 	bundlesURL = server.URL
 	defer func() { bundlesURL = oldURL }()
 
-	if _, err := fetchBundles(); err == nil {
+	if _, err := fetchBundles(time.Now()); err == nil {
 		t.Fatal("expected error on HTTP 403, got nil")
 	}
 	// 4xx is deterministic — retrying would just repeat the same failure.
@@ -220,7 +221,7 @@ func TestFetchBundlesOnceServerError(t *testing.T) {
 	bundlesURL = server.URL
 	defer func() { bundlesURL = oldURL }()
 
-	if _, err := fetchBundlesOnce(); err == nil {
+	if _, err := fetchBundlesOnce(time.Now()); err == nil {
 		t.Fatal("expected error on HTTP 500, got nil")
 	}
 }
@@ -0,0 +1,309 @@
+package gofanatical
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+)
+
+// History event types. The history file is an append-only log of these
+// events, one JSON object per line. Events are only appended when
+// something changed, or once per lastSeenBucket to record that a deal is
+// still live, so the file — like the feeds — stays byte-identical across
+// runs with unchanged input within a day.
+const (
+	eventSeen    = "seen"    // first time the deal was observed
+	eventPrice   = "price"   // price or discount changed
+	eventEnd     = "end"     // end date changed (usually an extension)
+	eventGone    = "gone"    // deal no longer returned by the API
+	eventPresent = "present" // deal still live, nothing changed
+)
+
+// lastSeenBucket is how often an unchanged deal gets an eventPresent.
+// It bounds how stale a reloaded LastSeen can be.
+const lastSeenBucket = 24 * time.Hour
+
+// HistoryEvent is one line of the history log.
+type HistoryEvent struct {
+	Type     string    `json:"type"`
+	Slug     string    `json:"slug"`
+	Start    int64     `json:"start"`
+	At       time.Time `json:"at"`
+	Title    string    `json:"title,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Currency string    `json:"currency,omitempty"`
+	Amount   float64   `json:"amount,omitempty"`
+	Original float64   `json:"original,omitempty"`
+	Discount int       `json:"discount,omitempty"`
+	EndDate  int64     `json:"end,omitempty"`
+}
+
+// HistoryRecord is the accumulated history of one deal, keyed like the
+// feed GUID by slug and start date.
+type HistoryRecord struct {
+	Slug      string
+	Title     string
+	Category  string
+	StartDate time.Time
+	FirstSeen time.Time
+	// LastSeen is the last run that confirmed the deal present. For
+	// removed deals it is the run that noticed the removal, so it is only
+	// as precise as the schedule interval. Reloaded from the log, it is
+	// the last event of the deal, at most lastSeenBucket old.
+	LastSeen time.Time
+	Active   bool
+	Prices   []PriceObservation
+	EndDates []EndDateObservation
+}
+
+// PriceObservation is a price in effect from At onwards.
+type PriceObservation struct {
+	At    time.Time
+	Price Price
+}
+
+// EndDateObservation is an end date announced at At.
+type EndDateObservation struct {
+	At      time.Time
+	EndDate time.Time
+}
+
+// CurrentPrice returns the most recently observed price.
+func (r *HistoryRecord) CurrentPrice() Price {
+	if len(r.Prices) == 0 {
+		return Price{}
+	}
+	return r.Prices[len(r.Prices)-1].Price
+}
+
+// CurrentEndDate returns the most recently observed end date.
+func (r *HistoryRecord) CurrentEndDate() time.Time {
+	if len(r.EndDates) == 0 {
+		return time.Time{}
+	}
+	return r.EndDates[len(r.EndDates)-1].EndDate
+}
+
+// History is the bundle history store backed by a JSON lines file.
+type History struct {
+	path    string
+	records map[string]*HistoryRecord
+	// readOnly makes Observe update the in-memory records only (dry runs).
+	readOnly bool
+}
+
+func historyKey(slug string, start time.Time) string {
+	return fmt.Sprintf("%s-%d", slug, start.Unix())
+}
+
+// openHistory replays the log at path. A missing file is an empty history.
+func openHistory(path string) (*History, error) {
+	h := &History{path: path, records: make(map[string]*HistoryRecord)}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return h, nil
+		}
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var ev HistoryEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("history %s line %d: %w", path, line, err)
+		}
+		h.apply(ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+
+	return h, nil
+}
+
+// apply folds one event into the in-memory records.
+func (h *History) apply(ev HistoryEvent) {
+	key := historyKey(ev.Slug, time.Unix(ev.Start, 0))
+	rec := h.records[key]
+	if rec == nil {
+		rec = &HistoryRecord{Slug: ev.Slug, StartDate: time.Unix(ev.Start, 0), FirstSeen: ev.At}
+		h.records[key] = rec
+	}
+	if ev.Title != "" {
+		rec.Title = ev.Title
+	}
+	if ev.Category != "" {
+		rec.Category = ev.Category
+	}
+	rec.LastSeen = ev.At
+
+	switch ev.Type {
+	case eventSeen, eventPrice, eventEnd:
+		rec.Active = true
+		if ev.Type != eventEnd {
+			rec.Prices = append(rec.Prices, PriceObservation{At: ev.At, Price: Price{
+				Currency: ev.Currency,
+				Amount:   ev.Amount,
+				Original: ev.Original,
+				Discount: ev.Discount,
+			}})
+		}
+		if ev.Type != eventPrice {
+			rec.EndDates = append(rec.EndDates, EndDateObservation{At: ev.At, EndDate: time.Unix(ev.EndDate, 0)})
+		}
+	case eventGone:
+		rec.Active = false
+	}
+}
+
+// Record returns the history of the deal with the given slug and start.
+func (h *History) Record(slug string, start time.Time) (*HistoryRecord, bool) {
+	rec, ok := h.records[historyKey(slug, start)]
+	return rec, ok
+}
+
+// startedBefore reports whether any deal was first seen before t, i.e.
+// whether the store already existed before the run at t.
+func (h *History) startedBefore(t time.Time) bool {
+	for _, rec := range h.records {
+		if rec.FirstSeen.Before(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Records returns every known deal, oldest start first.
+func (h *History) Records() []*HistoryRecord {
+	records := make([]*HistoryRecord, 0, len(h.records))
+	for _, rec := range h.records {
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].StartDate.Equal(records[j].StartDate) {
+			return records[i].StartDate.Before(records[j].StartDate)
+		}
+		return records[i].Slug < records[j].Slug
+	})
+	return records
+}
+
+// Observe compares the current snapshot with the stored history, appends
+// the resulting events to the log and returns them in key order.
+func (h *History) Observe(bundles []FanaticalBundle, now time.Time) ([]HistoryEvent, error) {
+	var events []HistoryEvent
+	present := make(map[string]bool, len(bundles))
+
+	for _, b := range bundles {
+		key := historyKey(b.Slug, b.StartDate)
+		present[key] = true
+
+		ev := HistoryEvent{
+			Slug:     b.Slug,
+			Start:    b.StartDate.Unix(),
+			At:       now,
+			Title:    b.Title,
+			Category: b.Category,
+			Currency: b.Price.Currency,
+			Amount:   b.Price.Amount,
+			Original: b.Price.Original,
+			Discount: b.Price.Discount,
+			EndDate:  b.EndDate.Unix(),
+		}
+
+		rec, known := h.records[key]
+		if !known || !rec.Active {
+			ev.Type = eventSeen
+			events = append(events, ev)
+			continue
+		}
+		changed := false
+		if rec.CurrentPrice() != b.Price {
+			priceEv := ev
+			priceEv.Type = eventPrice
+			priceEv.EndDate = 0
+			events = append(events, priceEv)
+			changed = true
+		}
+		if !rec.CurrentEndDate().Equal(b.EndDate) {
+			endEv := ev
+			endEv.Type = eventEnd
+			endEv.Currency, endEv.Amount, endEv.Original, endEv.Discount = "", 0, 0, 0
+			events = append(events, endEv)
+			changed = true
+		}
+		if !changed && !rec.LastSeen.UTC().Truncate(lastSeenBucket).Equal(now.UTC().Truncate(lastSeenBucket)) {
+			events = append(events, HistoryEvent{Type: eventPresent, Slug: b.Slug, Start: b.StartDate.Unix(), At: now})
+		}
+	}
+
+	for key, rec := range h.records {
+		if rec.Active && !present[key] {
+			events = append(events, HistoryEvent{Type: eventGone, Slug: rec.Slug, Start: rec.StartDate.Unix(), At: now})
+		}
+	}
+
+	// Deterministic order so identical runs append identical bytes.
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Start != events[j].Start {
+			return events[i].Start < events[j].Start
+		}
+		return events[i].Slug < events[j].Slug
+	})
+
+	if err := h.append(events); err != nil {
+		return nil, err
+	}
+	for _, ev := range events {
+		h.apply(ev)
+	}
+	// Every deal in this snapshot was confirmed present now, even when
+	// nothing about it changed and no event was written.
+	for key := range present {
+		h.records[key].LastSeen = now
+	}
+
+	slog.Info("history updated", "file", h.path, "events", len(events), "known_deals", len(h.records))
+
+	return events, nil
+}
+
+func (h *History) append(events []HistoryEvent) error {
+	if len(events) == 0 || h.readOnly {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history %s: %w", h.path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return fmt.Errorf("failed to encode history event: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write history %s: %w", h.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close history %s: %w", h.path, err)
+	}
+	return nil
+}
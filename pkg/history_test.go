@@ -0,0 +1,123 @@
+package gofanatical
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecordsChangesAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	start := time.Unix(1000, 0)
+	run1, run2, run3 := time.Unix(5000, 0), time.Unix(6000, 0), time.Unix(7000, 0)
+
+	alpha := testBundle("alpha", start)
+	beta := testBundle("beta", start)
+
+	h, err := openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := h.Observe([]FanaticalBundle{alpha, beta}, run1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Type != eventSeen || events[0].Slug != "alpha" {
+		t.Fatalf("first run: unexpected events %+v", events)
+	}
+
+	// Second run: alpha gets cheaper and extended, beta disappears.
+	alpha.Price.Amount = 1.99
+	alpha.EndDate = alpha.EndDate.Add(48 * time.Hour)
+	h, err = openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err = h.Observe([]FanaticalBundle{alpha}, run2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.Slug+":"+ev.Type)
+	}
+	want := []string{"alpha:price", "alpha:end", "beta:gone"}
+	if len(types) != len(want) {
+		t.Fatalf("second run events = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("second run events = %v, want %v", types, want)
+		}
+	}
+
+	// Third run with identical input must not grow the log.
+	before, _ := os.ReadFile(path)
+	h, err = openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, err = h.Observe([]FanaticalBundle{alpha}, run3); err != nil || len(events) != 0 {
+		t.Fatalf("unchanged run appended events %+v (err %v)", events, err)
+	}
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Error("history file changed on a run with identical input")
+	}
+
+	rec, ok := h.Record("alpha", start)
+	if !ok {
+		t.Fatal("alpha missing from history")
+	}
+	if !rec.FirstSeen.Equal(run1) || !rec.LastSeen.Equal(run3) || !rec.Active {
+		t.Errorf("alpha first/last seen = %v/%v active=%v", rec.FirstSeen, rec.LastSeen, rec.Active)
+	}
+	if len(rec.Prices) != 2 || rec.Prices[0].Price.Amount != 4.99 || rec.CurrentPrice().Amount != 1.99 {
+		t.Errorf("alpha price history = %+v", rec.Prices)
+	}
+	if len(rec.EndDates) != 2 || !rec.CurrentEndDate().Equal(alpha.EndDate) {
+		t.Errorf("alpha end date history = %+v", rec.EndDates)
+	}
+
+	gone, _ := h.Record("beta", start)
+	if gone.Active || !gone.LastSeen.Equal(run2) {
+		t.Errorf("beta should be inactive since run 2, got %+v", gone)
+	}
+
+	// Reloaded, LastSeen is the last logged event: run 3 wrote nothing,
+	// being in the same day as run 2.
+	h, err = openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, _ := h.Record("alpha", start); !rec.LastSeen.Equal(run2) {
+		t.Errorf("reloaded alpha last seen = %v, want %v", rec.LastSeen, run2)
+	}
+
+	// A day later an unchanged deal gets a heartbeat, so the reloaded
+	// LastSeen keeps up without a change event.
+	run4 := run3.Add(lastSeenBucket)
+	events, err = h.Observe([]FanaticalBundle{alpha}, run4)
+	if err != nil || len(events) != 1 || events[0].Type != eventPresent || events[0].Slug != "alpha" {
+		t.Fatalf("next-day run events = %+v (err %v), want one alpha present event", events, err)
+	}
+	h, err = openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, _ = h.Record("alpha", start)
+	if !rec.LastSeen.Equal(run4) || !rec.Active || len(rec.Prices) != 2 || len(rec.EndDates) != 2 {
+		t.Errorf("reloaded alpha after heartbeat = %+v", rec)
+	}
+}
+
+func TestOpenHistoryRejectsCorruptLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := os.WriteFile(path, []byte("{not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openHistory(path); err == nil {
+		t.Error("expected error for corrupt history log")
+	}
+}
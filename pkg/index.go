@@ -0,0 +1,102 @@
+package gofanatical
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+)
+
+//go:embed templates/index.html.tmpl
+var indexTemplateSource string
+
+var indexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"price": func(p Price) string { return formatAmount(p.Currency, p.Amount) },
+}).Parse(indexTemplateSource))
+
+// indexCard is the fixed copy shown on a landing page card. Icon selects
+// both the SVG and the feed-card--<icon> accent class.
+type indexCard struct {
+	Icon    string
+	Tag     string
+	Heading string
+	Blurb   string
+}
+
+// builtinCards keeps the hand-written copy of the built-in feeds. Other
+// feeds get a generic card from their title and description.
+var builtinCards = map[string]indexCard{
+	"books": {
+		Icon:    "books",
+		Tag:     "Book Bundles",
+		Heading: "Books & eBooks",
+		Blurb:   "Curated collections of eBooks and digital libraries at bundle prices. Technical, fiction, comics, and more.",
+	},
+	"games": {
+		Icon:    "games",
+		Tag:     "Game Bundles",
+		Heading: "Games & Gaming",
+		Blurb:   "The hottest game bundles with AAA titles and indie gems. Steam keys, DRM-free, and more at unbeatable prices.",
+	},
+	"software": {
+		Icon:    "software",
+		Tag:     "Software Bundles",
+		Heading: "Software & Tools",
+		Blurb:   "Professional software bundles for developers, designers, and creators. Productivity tools, creative suites, and utilities.",
+	},
+	endingSoonFeedName: {
+		Icon:    "clock",
+		Tag:     "Last Chance",
+		Heading: "Ending Soon",
+		Blurb:   "Bundles that end within the next 48 hours. Grab them before they're gone.",
+	},
+	changesFeedName: {
+		Icon:    "rss",
+		Tag:     "Deal Updates",
+		Heading: "Price Drops & Extensions",
+		Blurb:   "Price cuts and extended end dates of bundles that are already live.",
+	},
+}
+
+// indexFeed is one card on the landing page.
+type indexFeed struct {
+	File     string
+	Gzip     bool
+	Items    int
+	Newest   *FanaticalBundle
+	Cheapest *FanaticalBundle
+	Card     indexCard
+}
+
+// newIndexFeed summarizes a feed for the landing page. Ties are broken by
+// slug so the page is as deterministic as the feeds themselves.
+func newIndexFeed(name, title, description string, items int, bundles []FanaticalBundle, gzip bool) indexFeed {
+	card, ok := builtinCards[name]
+	if !ok {
+		card = indexCard{Icon: "rss", Tag: "Custom Feed", Heading: title, Blurb: description}
+	}
+	f := indexFeed{File: name + ".rss", Gzip: gzip, Items: items, Card: card}
+
+	for i := range bundles {
+		b := &bundles[i]
+		if f.Newest == nil || b.StartDate.After(f.Newest.StartDate) ||
+			b.StartDate.Equal(f.Newest.StartDate) && b.Slug < f.Newest.Slug {
+			f.Newest = b
+		}
+		if f.Cheapest == nil || b.Price.Amount < f.Cheapest.Price.Amount ||
+			b.Price.Amount == f.Cheapest.Price.Amount && b.Slug < f.Cheapest.Slug {
+			f.Cheapest = b
+		}
+	}
+	return f
+}
+
+// renderIndex renders the landing page. It depends only on the feeds'
+// content, never on the current time, so it changes only when they do.
+func renderIndex(feeds []indexFeed) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := indexTemplate.Execute(&buf, struct{ Feeds []indexFeed }{feeds}); err != nil {
+		return nil, fmt.Errorf("failed to render index.html: %w", err)
+	}
+	return buf.Bytes(), nil
+}
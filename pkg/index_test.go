@@ -0,0 +1,48 @@
+package gofanatical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderIndexListsFeedsWithStats(t *testing.T) {
+	cheap := testBundle("cheap", time.Date(2030, time.January, 5, 0, 0, 0, 0, time.UTC))
+	cheap.Title = "Cheap <Deal>"
+	cheap.Price.Amount = 0.99
+	newest := testBundle("newest", time.Date(2030, time.February, 1, 0, 0, 0, 0, time.UTC))
+
+	page, err := renderIndex([]indexFeed{
+		newIndexFeed("games", "Fanatical RSS Games Bundles", "", 2, []FanaticalBundle{cheap, newest}, true),
+		newIndexFeed("cheap-linux", "Cheap Linux Games", "Linux games under $5", 0, nil, false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+
+	for _, want := range []string{
+		`<a href="games.rss" class="feed-card feed-card--games">`,
+		"Games &amp; Gaming",
+		"<dt>Deals</dt><dd>2</dd>",
+		"Bundle newest",
+		"Feb 1, 2030",
+		"Cheap &lt;Deal&gt;",
+		"$0.99",
+		"Also as games.rss.gz",
+		`<a href="cheap-linux.rss" class="feed-card feed-card--rss">`,
+		"Cheap Linux Games",
+		"Linux games under $5",
+		"<dt>Deals</dt><dd>0</dd>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("index.html missing %q", want)
+		}
+	}
+	if !strings.HasPrefix(html, "<!DOCTYPE html>") {
+		t.Error("index.html must start with the doctype")
+	}
+	if strings.Count(html, "cheap-linux.rss.gz") != 0 {
+		t.Error("gzip link shown for a feed without gzip variant")
+	}
+}
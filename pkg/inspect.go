@@ -0,0 +1,178 @@
+package gofanatical
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Bundle inspection for the list and show subcommands.
+
+// CurrentBundles fetches the bundles currently on sale, deduplicated and
+// newest first, without generating anything.
+func CurrentBundles(opts Options) ([]FanaticalBundle, error) {
+	configureLogging()
+	bundles, err := fetchBundles(opts.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bundles: %w", err)
+	}
+	bundles = removeDuplicateBundles(bundles)
+	sortBundlesNewestFirst(bundles)
+	return bundles, nil
+}
+
+// BundleFilter selects bundles. The fields take the same values as the
+// /feed.rss parameters of the same name; empty fields don't filter.
+type BundleFilter struct {
+	Category string
+	MinPrice string
+	MaxPrice string
+	// Query is an additional expression in the feed query language.
+	Query string
+}
+
+// Apply returns the bundles matching every field of f, in order.
+func (f BundleFilter) Apply(bundles []FanaticalBundle) ([]FanaticalBundle, error) {
+	params := url.Values{}
+	for name, v := range map[string]string{"category": f.Category, "min_price": f.MinPrice, "max_price": f.MaxPrice} {
+		if v != "" {
+			params.Set(name, v)
+		}
+	}
+	expr, err := dynamicFeedQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	if f.Query != "" {
+		expr = "(" + expr + ") && (" + f.Query + ")"
+	}
+	match, err := compileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []FanaticalBundle
+	for _, b := range bundles {
+		if match(b) {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered, nil
+}
+
+// FindBundle returns the bundle with the given slug. A slug that ran more
+// than once resolves to the newest run.
+func FindBundle(bundles []FanaticalBundle, slug string) (FanaticalBundle, bool) {
+	var found *FanaticalBundle
+	for i, b := range bundles {
+		if b.Slug == slug && (found == nil || b.StartDate.After(found.StartDate)) {
+			found = &bundles[i]
+		}
+	}
+	if found == nil {
+		return FanaticalBundle{}, false
+	}
+	return *found, true
+}
+
+// Output formats of WriteBundles.
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+)
+
+// maxTableTitle keeps table rows on one terminal line.
+const maxTableTitle = 48
+
+// WriteBundles prints bundles as an aligned table, CSV, or JSON. JSON
+// uses the representation of the server's /api/bundles. Links point to
+// the site's store.
+func (s Site) WriteBundles(w io.Writer, bundles []FanaticalBundle, format string) error {
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TITLE\tCATEGORY\tPRICE\tDISCOUNT\tENDS\tSLUG")
+		for _, b := range bundles {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d%%\t%s\t%s\n", truncateTitle(b.Title), b.Category,
+				formatAmount(b.Price.Currency, b.Price.Amount), b.Price.Discount,
+				b.EndDate.UTC().Format("2006-01-02 15:04"), b.Slug)
+		}
+		return tw.Flush()
+
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"slug", "title", "category", "currency", "price", "original_price", "discount", "start_date", "end_date", "url"})
+		for _, b := range bundles {
+			cw.Write([]string{
+				b.Slug, b.Title, b.Category, b.Price.Currency,
+				strconv.FormatFloat(b.Price.Amount, 'f', 2, 64),
+				strconv.FormatFloat(b.Price.Original, 'f', 2, 64),
+				strconv.Itoa(b.Price.Discount),
+				b.StartDate.UTC().Format(time.RFC3339), b.EndDate.UTC().Format(time.RFC3339),
+				s.bundleURL(b),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case FormatJSON:
+		list := make([]apiBundle, len(bundles))
+		for i, b := range bundles {
+			list[i] = apiBundleFor(b, s)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	return fmt.Errorf("unknown format %q: use %s, %s, or %s", format, FormatTable, FormatCSV, FormatJSON)
+}
+
+func truncateTitle(title string) string {
+	runes := []rune(title)
+	if len(runes) <= maxTableTitle {
+		return title
+	}
+	return string(runes[:maxTableTitle-1]) + "…"
+}
+
+// WriteBundleDetails prints every field of one bundle.
+func (s Site) WriteBundleDetails(w io.Writer, b FanaticalBundle) error {
+	price := formatAmount(b.Price.Currency, b.Price.Amount)
+	if b.Price.Original > 0 {
+		price += fmt.Sprintf(" (was %s, -%d%%)", formatAmount(b.Price.Currency, b.Price.Original), b.Price.Discount)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	for _, field := range [][2]string{
+		{"Title", b.Title},
+		{"Slug", b.Slug},
+		{"Category", b.Category},
+		{"Price", price},
+		{"Starts", b.StartDate.UTC().Format("January 2, 2006 15:04 MST")},
+		{"Ends", b.EndDate.UTC().Format("January 2, 2006 15:04 MST")},
+		{"OS", strings.Join(b.OperatingSystems, ", ")},
+		{"DRM", strings.Join(b.DRM, ", ")},
+		{"URL", s.bundleURL(b)},
+		{"Image", b.Image},
+		{"GUID", bundleGUID(b)},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", field[0], field[1])
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if b.Description != "" {
+		_, err := fmt.Fprintf(w, "\n%s\n", b.Description)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package gofanatical
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func inspectBundles() []FanaticalBundle {
+	game := testBundle("game", time.Date(2030, time.March, 1, 17, 0, 0, 0, time.UTC))
+	game.Category = "games"
+	game.Price.Amount = 1.99
+	game.EndDate = time.Date(2030, time.March, 8, 17, 0, 0, 0, time.UTC)
+	game.Title = "A Very Long Bundle Title That Will Not Fit Into The Table Column"
+	book := testBundle("book", time.Date(2030, time.February, 1, 17, 0, 0, 0, time.UTC))
+	book.Category = "books"
+	book.EndDate = time.Date(2030, time.March, 3, 17, 0, 0, 0, time.UTC)
+	book.Description = "Twelve ebooks."
+	book.DRM = []string{"drm-free"}
+	return []FanaticalBundle{game, book}
+}
+
+func TestBundleFilter(t *testing.T) {
+	bundles := inspectBundles()
+	for _, tt := range []struct {
+		filter BundleFilter
+		want   []string
+	}{
+		{BundleFilter{}, []string{"game", "book"}},
+		{BundleFilter{Category: "books"}, []string{"book"}},
+		{BundleFilter{MaxPrice: "2"}, []string{"game"}},
+		{BundleFilter{MinPrice: "2", Category: "games,books"}, []string{"book"}},
+		{BundleFilter{Query: `"drm-free" in drm`}, []string{"book"}},
+		{BundleFilter{Category: "games", Query: `"drm-free" in drm`}, nil},
+	} {
+		got, err := tt.filter.Apply(bundles)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.filter, err)
+			continue
+		}
+		var slugs []string
+		for _, b := range got {
+			slugs = append(slugs, b.Slug)
+		}
+		if strings.Join(slugs, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v: got %v, want %v", tt.filter, slugs, tt.want)
+		}
+	}
+
+	for _, bad := range []BundleFilter{{Category: "movies"}, {MaxPrice: "cheap"}, {Query: "price <"}} {
+		if _, err := bad.Apply(bundles); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+}
+
+func TestWriteBundles(t *testing.T) {
+	bundles := inspectBundles()
+
+	var table strings.Builder
+	if err := defaultSite.WriteBundles(&table, bundles, FormatTable); err != nil {
+		t.Fatal(err)
+	}
+	want := `TITLE                                             CATEGORY  PRICE  DISCOUNT  ENDS              SLUG
+A Very Long Bundle Title That Will Not Fit Into…  games     $1.99  50%       2030-03-08 17:00  game
+Bundle book                                       books     $4.99  50%       2030-03-03 17:00  book
+`
+	if table.String() != want {
+		t.Errorf("table:\n%s\nwant:\n%s", table.String(), want)
+	}
+
+	var out strings.Builder
+	if err := defaultSite.WriteBundles(&out, bundles, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2][0] != "book" || records[2][4] != "4.99" || records[2][8] != "2030-03-03T17:00:00Z" ||
+		records[2][9] != "https://www.fanatical.com/en/bundle/book" {
+		t.Errorf("csv: %v", records)
+	}
+
+	out.Reset()
+	if err := defaultSite.WriteBundles(&out, bundles, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []apiBundle
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1].GUID != bundleGUID(bundles[1]) || decoded[1].Description != "Twelve ebooks." {
+		t.Errorf("json: %+v", decoded)
+	}
+
+	if err := defaultSite.WriteBundles(&out, bundles, "yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestWriteBundleDetails(t *testing.T) {
+	book := inspectBundles()[1]
+	b, ok := FindBundle(inspectBundles(), "book")
+	if !ok || b.Slug != "book" {
+		t.Fatalf("FindBundle: %v %v", b, ok)
+	}
+	if _, ok := FindBundle(inspectBundles(), "gone"); ok {
+		t.Error("FindBundle found a missing slug")
+	}
+
+	var out strings.Builder
+	if err := defaultSite.WriteBundleDetails(&out, book); err != nil {
+		t.Fatal(err)
+	}
+	want := `Title:    Bundle book
+Slug:     book
+Category: books
+Price:    $4.99 (was $9.99, -50%)
+Starts:   February 1, 2030 17:00 UTC
+Ends:     March 3, 2030 17:00 UTC
+DRM:      drm-free
+URL:      https://www.fanatical.com/en/bundle/book
+GUID:     fanatical-book-1896195600
+
+Twelve ebooks.
+`
+	if out.String() != want {
+		t.Errorf("details:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
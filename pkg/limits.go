@@ -0,0 +1,108 @@
+package gofanatical
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// FeedLimits caps the size of a feed. Zero values mean "no limit".
+//
+// Feeds are ordered newest first, so every limit truncates from the end:
+// items older than MaxAgeDays go first, then everything past MaxItems,
+// then further old items until the rendered RSS fits into MaxBytes.
+type FeedLimits struct {
+	MaxItems   int `json:"max_items"`
+	MaxAgeDays int `json:"max_age_days"`
+	MaxBytes   int `json:"max_bytes"`
+}
+
+// limitsFor returns the limits of the named feed: its entry in
+// feed_limits if present, otherwise the global limits.
+func (c Config) limitsFor(name string) FeedLimits {
+	if limits, ok := c.FeedLimits[name]; ok {
+		return limits
+	}
+	return c.Limits
+}
+
+// applyLimits truncates feed.Items in place and returns how many items
+// were dropped. Items must already be sorted newest first, as createFeed
+// and createChangesFeed do, so the kept items are always a prefix. The
+// feed's language is needed to measure max_bytes exactly.
+func applyLimits(feed *feeds.Feed, limits FeedLimits, now time.Time, language string) (int, error) {
+	total := len(feed.Items)
+	keep := total
+
+	if limits.MaxAgeDays > 0 {
+		// Counted from the start of the UTC day, so every run of a day
+		// keeps the same items.
+		cutoff := now.UTC().Truncate(24 * time.Hour).Add(-time.Duration(limits.MaxAgeDays) * 24 * time.Hour)
+		keep = sort.Search(keep, func(i int) bool { return feed.Items[i].Created.Before(cutoff) })
+	}
+	if limits.MaxItems > 0 && keep > limits.MaxItems {
+		keep = limits.MaxItems
+	}
+	feed.Items = feed.Items[:keep]
+
+	if limits.MaxBytes > 0 {
+		items := feed.Items
+		fits := func(n int) (bool, error) {
+			feed.Items = items[:n]
+			rss, err := renderRSS(*feed, language)
+			if err != nil {
+				return false, err
+			}
+			return len(rss) <= limits.MaxBytes, nil
+		}
+
+		ok, err := fits(keep)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			// Largest prefix that still fits; size grows with every item.
+			lo, hi := 0, keep-1
+			for lo < hi {
+				mid := (lo + hi + 1) / 2
+				ok, err := fits(mid)
+				if err != nil {
+					return 0, err
+				}
+				if ok {
+					lo = mid
+				} else {
+					hi = mid - 1
+				}
+			}
+			keep = lo
+			if keep == 0 {
+				slog.Warn("feed exceeds max_bytes even without items", "feed", feed.Title, "max_bytes", limits.MaxBytes)
+			}
+		}
+		feed.Items = items[:keep]
+	}
+
+	if dropped := total - keep; dropped > 0 {
+		slog.Info("feed truncated by limits", "feed", feed.Title, "kept", keep, "dropped", dropped)
+	}
+	return total - keep, nil
+}
+
+// keptBundles returns the bundles whose items are still in the feed, in
+// item order.
+func keptBundles(bundles []FanaticalBundle, items []*feeds.Item) []FanaticalBundle {
+	byGUID := make(map[string]FanaticalBundle, len(bundles))
+	for _, b := range bundles {
+		byGUID[bundleGUID(b)] = b
+	}
+	kept := make([]FanaticalBundle, 0, len(items))
+	for _, item := range items {
+		if b, ok := byGUID[item.Id]; ok {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,110 @@
+package gofanatical
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func limitTestFeed(n int) []FanaticalBundle {
+	var bundles []FanaticalBundle
+	for i := 0; i < n; i++ {
+		// Day i: the highest index is the newest bundle.
+		bundles = append(bundles, testBundle(string(rune('a'+i)), time.Unix(int64(i)*86400, 0)))
+	}
+	return bundles
+}
+
+func TestApplyLimitsMaxItemsKeepsNewest(t *testing.T) {
+	feed := createFeed(limitTestFeed(5), categoryFeed(defaultSite, "games"))
+
+	dropped, err := applyLimits(&feed, FeedLimits{MaxItems: 2}, time.Unix(10*86400, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 3 || len(feed.Items) != 2 {
+		t.Fatalf("dropped=%d kept=%d, want 3/2", dropped, len(feed.Items))
+	}
+	if feed.Items[0].Title != "Bundle e" || feed.Items[1].Title != "Bundle d" {
+		t.Errorf("kept %q, %q; want the two newest", feed.Items[0].Title, feed.Items[1].Title)
+	}
+}
+
+func TestApplyLimitsMaxAge(t *testing.T) {
+	feed := createFeed(limitTestFeed(5), categoryFeed(defaultSite, "games"))
+
+	// Now is day 5: the bundles from day 3 and 4 started within two days.
+	if _, err := applyLimits(&feed, FeedLimits{MaxAgeDays: 2}, time.Unix(5*86400, 0), ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Items) != 2 || feed.Items[1].Title != "Bundle d" {
+		t.Errorf("kept %d items, want the 2 from the last two days", len(feed.Items))
+	}
+}
+
+func TestApplyLimitsMaxAgeIsStableWithinADay(t *testing.T) {
+	var kept []int
+	for _, hour := range []int64{0, 9, 23} {
+		feed := createFeed(limitTestFeed(5), categoryFeed(defaultSite, "games"))
+		if _, err := applyLimits(&feed, FeedLimits{MaxAgeDays: 2}, time.Unix(5*86400+hour*3600, 0), ""); err != nil {
+			t.Fatal(err)
+		}
+		kept = append(kept, len(feed.Items))
+	}
+	if kept[0] != 2 || kept[1] != 2 || kept[2] != 2 {
+		t.Errorf("kept %v items over the day, want 2 every run", kept)
+	}
+}
+
+func TestKeptBundles(t *testing.T) {
+	bundles := limitTestFeed(5)
+	feed := createFeed(slices.Clone(bundles), categoryFeed(defaultSite, "games"))
+	feed.Items = feed.Items[:2]
+
+	kept := keptBundles(bundles, feed.Items)
+	if len(kept) != 2 || kept[0].Slug != "e" || kept[1].Slug != "d" {
+		t.Errorf("kept %+v, want e and d in item order", kept)
+	}
+}
+
+func TestApplyLimitsMaxBytes(t *testing.T) {
+	full := createFeed(limitTestFeed(5), categoryFeed(defaultSite, "games"))
+	rssFull, _ := full.ToRss()
+
+	one := createFeed(limitTestFeed(5), categoryFeed(defaultSite, "games"))
+	one.Items = one.Items[:1]
+	rssOne, _ := one.ToRss()
+
+	// Budget for one item plus half of another: exactly one must stay.
+	limit := len(rssOne) + (len(rssFull)-len(rssOne))/8
+	feed := createFeed(limitTestFeed(5), categoryFeed(defaultSite, "games"))
+	if _, err := applyLimits(&feed, FeedLimits{MaxBytes: limit}, time.Unix(10*86400, 0), ""); err != nil {
+		t.Fatal(err)
+	}
+	rss, _ := feed.ToRss()
+	if len(rss) > limit {
+		t.Errorf("rendered %d bytes, limit %d", len(rss), limit)
+	}
+	if len(feed.Items) != 1 || feed.Items[0].Title != "Bundle e" {
+		t.Errorf("kept %d items, want only the newest", len(feed.Items))
+	}
+
+	// A limit the whole feed fits into changes nothing.
+	feed = createFeed(limitTestFeed(5), categoryFeed(defaultSite, "games"))
+	if dropped, _ := applyLimits(&feed, FeedLimits{MaxBytes: len(rssFull)}, time.Unix(10*86400, 0), ""); dropped != 0 {
+		t.Errorf("dropped %d items from a feed within its byte limit", dropped)
+	}
+}
+
+func TestConfigLimitsFor(t *testing.T) {
+	cfg := Config{
+		Limits:     FeedLimits{MaxItems: 50},
+		FeedLimits: map[string]FeedLimits{"games": {MaxBytes: 1000}},
+	}
+	if got := cfg.limitsFor("books"); got.MaxItems != 50 {
+		t.Errorf("books limits = %+v, want the global limits", got)
+	}
+	if got := cfg.limitsFor("games"); got.MaxItems != 0 || got.MaxBytes != 1000 {
+		t.Errorf("games limits = %+v, want the per-feed override", got)
+	}
+}
@@ -0,0 +1,206 @@
+package gofanatical
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultLocale is the locale of the bundle paths returned by the API.
+const defaultLocale = "en"
+
+// ItemLabels are the fixed texts of the built-in item template, in the
+// feed's locale.
+type ItemLabels struct {
+	CurrentPrice  string
+	OriginalPrice string
+	Discount      string
+	YouSave       string
+	Availability  string
+	Ends          string
+	GetDeal       string
+	// Free and NotAvailable stand in for prices in ItemData.
+	Free         string
+	NotAvailable string
+}
+
+// locale holds everything that differs between the store languages.
+type locale struct {
+	// language is the RSS channel language of the locale's feeds.
+	language string
+	labels   ItemLabels
+	// dateLayout formats the "Ends" line; the English month name it
+	// produces is replaced with months.
+	dateLayout string
+	months     [12]string
+	// categoryTitles take the site name; endingSoonTitle and
+	// changesTitle too.
+	categoryTitles       map[string]string
+	categoryDescriptions map[string]string
+	endingSoonTitle      string
+	endingSoonDesc       string
+	changesTitle         string
+	changesDesc          string
+	// priceDropped and priceRose take the old and the new price,
+	// extendedUntil and nowEnds the new end date.
+	priceDropped  string
+	priceRose     string
+	extendedUntil string
+	nowEnds       string
+}
+
+// locales are the Fanatical store locales, keyed by their path prefix
+// (fanatical.com/de/...).
+var locales = map[string]locale{
+	"en": {
+		language: "en",
+		labels: ItemLabels{
+			CurrentPrice: "Current Price", OriginalPrice: "Original Price", Discount: "Discount", YouSave: "You Save",
+			Availability: "Availability", Ends: "Ends:", GetDeal: "Get this deal on Fanatical",
+			Free: "FREE", NotAvailable: "N/A",
+		},
+		dateLayout: "January 2, 2006 15:04 MST",
+		months: [12]string{"January", "February", "March", "April", "May", "June",
+			"July", "August", "September", "October", "November", "December"},
+		categoryTitles: map[string]string{"books": "%s Books Bundles", "games": "%s Games Bundles", "software": "%s Software Bundles"},
+		categoryDescriptions: map[string]string{
+			"books":    "Latest Fanatical books bundles with amazing deals and discounts!",
+			"games":    "Latest Fanatical games bundles with amazing deals and discounts!",
+			"software": "Latest Fanatical software bundles with amazing deals and discounts!",
+		},
+		endingSoonTitle: "%s Ending Soon",
+		endingSoonDesc:  "Fanatical bundles ending within the next 48 hours",
+		changesTitle:    "%s Deal Updates",
+		changesDesc:     "Price drops and extensions of current Fanatical bundles",
+		priceDropped:    "Price dropped from %s to %s",
+		priceRose:       "Price rose from %s to %s",
+		extendedUntil:   "Extended until %s",
+		nowEnds:         "Now ends %s",
+	},
+	"de": {
+		language: "de",
+		labels: ItemLabels{
+			CurrentPrice: "Aktueller Preis", OriginalPrice: "Originalpreis", Discount: "Rabatt", YouSave: "Du sparst",
+			Availability: "Verfügbarkeit", Ends: "Endet:", GetDeal: "Zum Angebot auf Fanatical",
+			Free: "GRATIS", NotAvailable: "k. A.",
+		},
+		dateLayout: "2. January 2006 15:04 MST",
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni",
+			"Juli", "August", "September", "Oktober", "November", "Dezember"},
+		categoryTitles: map[string]string{"books": "%s Bücher-Bundles", "games": "%s Spiele-Bundles", "software": "%s Software-Bundles"},
+		categoryDescriptions: map[string]string{
+			"books":    "Die neuesten Bücher-Bundles von Fanatical mit tollen Angeboten und Rabatten!",
+			"games":    "Die neuesten Spiele-Bundles von Fanatical mit tollen Angeboten und Rabatten!",
+			"software": "Die neuesten Software-Bundles von Fanatical mit tollen Angeboten und Rabatten!",
+		},
+		endingSoonTitle: "%s – Endet bald",
+		endingSoonDesc:  "Fanatical-Bundles, die in den nächsten 48 Stunden enden",
+		changesTitle:    "%s – Angebots-Updates",
+		changesDesc:     "Preissenkungen und Verlängerungen aktueller Fanatical-Bundles",
+		priceDropped:    "Preis gesenkt von %s auf %s",
+		priceRose:       "Preis erhöht von %s auf %s",
+		extendedUntil:   "Verlängert bis %s",
+		nowEnds:         "Endet jetzt am %s",
+	},
+	"fr": {
+		language: "fr",
+		labels: ItemLabels{
+			CurrentPrice: "Prix actuel", OriginalPrice: "Prix d'origine", Discount: "Remise", YouSave: "Économie",
+			Availability: "Disponibilité", Ends: "Fin :", GetDeal: "Voir l'offre sur Fanatical",
+			Free: "GRATUIT", NotAvailable: "N/D",
+		},
+		dateLayout: "2 January 2006 15:04 MST",
+		months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin",
+			"juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		categoryTitles: map[string]string{"books": "%s Bundles de livres", "games": "%s Bundles de jeux", "software": "%s Bundles de logiciels"},
+		categoryDescriptions: map[string]string{
+			"books":    "Les derniers bundles de livres Fanatical à prix réduits !",
+			"games":    "Les derniers bundles de jeux Fanatical à prix réduits !",
+			"software": "Les derniers bundles de logiciels Fanatical à prix réduits !",
+		},
+		endingSoonTitle: "%s Bientôt terminés",
+		endingSoonDesc:  "Bundles Fanatical se terminant dans les 48 prochaines heures",
+		changesTitle:    "%s Mises à jour des offres",
+		changesDesc:     "Baisses de prix et prolongations des bundles Fanatical en cours",
+		priceDropped:    "Prix baissé de %s à %s",
+		priceRose:       "Prix augmenté de %s à %s",
+		extendedUntil:   "Prolongé jusqu'au %s",
+		nowEnds:         "Se termine désormais le %s",
+	},
+	"es": {
+		language: "es",
+		labels: ItemLabels{
+			CurrentPrice: "Precio actual", OriginalPrice: "Precio original", Discount: "Descuento", YouSave: "Ahorras",
+			Availability: "Disponibilidad", Ends: "Termina:", GetDeal: "Ver la oferta en Fanatical",
+			Free: "GRATIS", NotAvailable: "N/D",
+		},
+		dateLayout: "2 de January de 2006 15:04 MST",
+		months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio",
+			"julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		categoryTitles: map[string]string{"books": "%s Bundles de libros", "games": "%s Bundles de juegos", "software": "%s Bundles de software"},
+		categoryDescriptions: map[string]string{
+			"books":    "¡Los últimos bundles de libros de Fanatical con grandes descuentos!",
+			"games":    "¡Los últimos bundles de juegos de Fanatical con grandes descuentos!",
+			"software": "¡Los últimos bundles de software de Fanatical con grandes descuentos!",
+		},
+		endingSoonTitle: "%s Terminan pronto",
+		endingSoonDesc:  "Bundles de Fanatical que terminan en las próximas 48 horas",
+		changesTitle:    "%s Novedades de ofertas",
+		changesDesc:     "Bajadas de precio y ampliaciones de los bundles actuales de Fanatical",
+		priceDropped:    "El precio bajó de %s a %s",
+		priceRose:       "El precio subió de %s a %s",
+		extendedUntil:   "Ampliado hasta el %s",
+		nowEnds:         "Ahora termina el %s",
+	},
+	"it": {
+		language: "it",
+		labels: ItemLabels{
+			CurrentPrice: "Prezzo attuale", OriginalPrice: "Prezzo originale", Discount: "Sconto", YouSave: "Risparmi",
+			Availability: "Disponibilità", Ends: "Termina:", GetDeal: "Vai all'offerta su Fanatical",
+			Free: "GRATIS", NotAvailable: "N/D",
+		},
+		dateLayout: "2 January 2006 15:04 MST",
+		months: [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno",
+			"luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		categoryTitles: map[string]string{"books": "%s Bundle di libri", "games": "%s Bundle di giochi", "software": "%s Bundle di software"},
+		categoryDescriptions: map[string]string{
+			"books":    "Gli ultimi bundle di libri di Fanatical a prezzi scontati!",
+			"games":    "Gli ultimi bundle di giochi di Fanatical a prezzi scontati!",
+			"software": "Gli ultimi bundle di software di Fanatical a prezzi scontati!",
+		},
+		endingSoonTitle: "%s In scadenza",
+		endingSoonDesc:  "Bundle di Fanatical che scadono nelle prossime 48 ore",
+		changesTitle:    "%s Aggiornamenti delle offerte",
+		changesDesc:     "Ribassi di prezzo e proroghe dei bundle Fanatical in corso",
+		priceDropped:    "Prezzo sceso da %s a %s",
+		priceRose:       "Prezzo salito da %s a %s",
+		extendedUntil:   "Prorogato fino al %s",
+		nowEnds:         "Ora termina il %s",
+	},
+}
+
+// lookupLocale returns the locale with the given code.
+func lookupLocale(code string) (locale, error) {
+	l, ok := locales[code]
+	if !ok {
+		return l, fmt.Errorf("unknown locale %q (supported: %s)", code, strings.Join(slices.Sorted(maps.Keys(locales)), ", "))
+	}
+	return l, nil
+}
+
+// formatDate formats t in UTC the way the locale writes dates.
+func (l locale) formatDate(t time.Time) string {
+	t = t.UTC()
+	return strings.Replace(t.Format(l.dateLayout), t.Month().String(), l.months[t.Month()-1], 1)
+}
+
+// localizedPath moves a store path such as /en/bundle/<slug> into the
+// given locale.
+func localizedPath(path, code string) string {
+	if rest, ok := strings.CutPrefix(path, "/"+defaultLocale+"/"); ok {
+		return "/" + code + "/" + rest
+	}
+	return path
+}
@@ -0,0 +1,126 @@
+package gofanatical
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocaleFormatDate(t *testing.T) {
+	end := time.Date(2030, time.March, 4, 13, 5, 0, 0, time.FixedZone("CET", 3600))
+	for code, want := range map[string]string{
+		"en": "March 4, 2030 12:05 UTC",
+		"de": "4. März 2030 12:05 UTC",
+		"fr": "4 mars 2030 12:05 UTC",
+		"es": "4 de marzo de 2030 12:05 UTC",
+		"it": "4 marzo 2030 12:05 UTC",
+	} {
+		if got := locales[code].formatDate(end); got != want {
+			t.Errorf("%s: formatDate = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestLocalizedPath(t *testing.T) {
+	for _, tc := range []struct{ path, code, want string }{
+		{"/en/bundle/killer-42", "de", "/de/bundle/killer-42"},
+		{"/en/pick-and-mix/build", "fr", "/fr/pick-and-mix/build"},
+		{"/en/game/x", "en", "/en/game/x"},
+		{"/bundle/odd", "de", "/bundle/odd"},
+	} {
+		if got := localizedPath(tc.path, tc.code); got != tc.want {
+			t.Errorf("localizedPath(%q, %q) = %q, want %q", tc.path, tc.code, got, tc.want)
+		}
+	}
+}
+
+func TestRunPublishesLocalizedFeeds(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	stubBundlesAPI(t, fmt.Sprintf(`[{"name": "Killer Bundle 42", "slug": "killer-42", "type": "bundle",
+		"on_sale": true, "price": {"USD": 0}, "fullPrice": {"USD": 49.99},
+		"available_valid_from": 1000, "available_valid_until": %d}]`, now.Add(24*time.Hour).Unix()))
+	writeConfig(t, fmt.Sprintf(`{
+		"site": {"language": "en-us", "locales": ["de"], "feeds": {"books.de": {"title": "Bücher"}}},
+		"feeds": [{"name": "cheap", "query": "price < 5"}],
+		"history_file": %q,
+		"changes_feed": true
+	}`, filepath.Join(t.TempDir(), "history.jsonl")))
+	out := t.TempDir()
+	if _, err := Run(Options{Clock: func() time.Time { return now }, OutDir: out}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	games := read("games.de.rss")
+	for _, want := range []string{
+		"<title>Fanatical RSS Spiele-Bundles</title>",
+		"<language>de</language>",
+		"<link>https://www.fanatical.com/de/bundle/killer-42</link>",
+		"Aktueller Preis", "<strong>GRATIS</strong>", "Endet:</strong> 2. März 2030 12:00 UTC", "Zum Angebot auf Fanatical",
+	} {
+		if !strings.Contains(games, want) {
+			t.Errorf("games.de.rss is missing %s:\n%s", want, games)
+		}
+	}
+	for _, name := range []string{"ending-soon.de.rss", "cheap.de.rss"} {
+		if feed := read(name); !strings.Contains(feed, "/de/bundle/killer-42") {
+			t.Errorf("%s does not link the German store:\n%s", name, feed)
+		}
+	}
+	if changes := read("changes.de.rss"); !strings.Contains(changes, "<title>Fanatical RSS – Angebots-Updates</title>") ||
+		!strings.Contains(changes, "<language>de</language>") {
+		t.Errorf("changes.de.rss is not German:\n%s", changes)
+	}
+	if books := read("books.de.rss"); !strings.Contains(books, "<title>Bücher</title>") {
+		t.Errorf("site.feeds override for books.de not applied:\n%s", books)
+	}
+	if english := read("games.rss"); !strings.Contains(english, "/en/bundle/killer-42") || !strings.Contains(english, "<language>en-us</language>") ||
+		strings.Contains(english, "Aktueller Preis") {
+		t.Errorf("games.rss should stay English:\n%s", english)
+	}
+}
+
+func TestLoadConfigRejectsBadLocales(t *testing.T) {
+	for config, want := range map[string]string{
+		`{"site": {"locale": "xx"}}`:                          "unknown locale",
+		`{"site": {"locales": ["de", "klingon"]}}`:            "unknown locale",
+		`{"site": {"locales": ["en"]}}`:                       "already the main locale",
+		`{"site": {"locale": "de", "locales": ["fr", "de"]}}`: "already the main locale",
+	} {
+		writeConfig(t, config)
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", config, err, want)
+		}
+	}
+}
+
+func TestMainLocaleSetsChannelLanguage(t *testing.T) {
+	for config, want := range map[string]string{
+		`{"site": {"locale": "de"}}`:                      "de",
+		`{"site": {"locale": "de", "language": "de-at"}}`: "de-at",
+		`{}`: "",
+	} {
+		writeConfig(t, config)
+		cfg, err := loadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defs, err := feedDefinitions(cfg, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if defs[0].Language != want {
+			t.Errorf("%s: %s language = %q, want %q", config, defs[0].Name, defs[0].Language, want)
+		}
+	}
+}
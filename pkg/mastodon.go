@@ -0,0 +1,387 @@
+package gofanatical
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// MastodonConfig enables posting new bundles as statuses.
+type MastodonConfig struct {
+	// Server is the instance base URL, e.g. "https://mastodon.social".
+	Server string `json:"server"`
+	// AccessToken needs the write:statuses and write:media scopes; when
+	// empty, MASTODON_ACCESS_TOKEN is used.
+	AccessToken string `json:"access_token"`
+	// Template is a text/template for the status; see mastodonStatus for
+	// the fields. Empty means defaultMastodonTemplate.
+	Template string `json:"template"`
+	// Visibility is public (default), unlisted, private or direct.
+	Visibility string `json:"visibility"`
+	// StateFile lists the GUIDs already posted, one per line. Empty means
+	// defaultMastodonStateFile.
+	StateFile string `json:"state_file"`
+}
+
+const (
+	defaultMastodonTemplate = `New on Fanatical: {{.Title}}
+{{.Price}}{{if .Original}} (was {{.Original}}, -{{.Discount}}%){{end}} · ends {{.Ends}}
+
+{{.Description}}
+
+{{.URL}}`
+	defaultMastodonStateFile = "mastodon-posted.txt"
+	// defaultMastodonLimit applies when the instance does not report one.
+	defaultMastodonLimit = 500
+	// mastodonURLLength is what Mastodon counts for any link.
+	mastodonURLLength = 23
+	mastodonAttempts  = 3
+)
+
+// mastodonStatus is the data available to the status template.
+type mastodonStatus struct {
+	Title       string
+	Description string
+	Category    string
+	Price       string
+	Original    string // empty when there is no original price
+	Discount    int
+	Ends        string
+	URL         string
+}
+
+// MastodonNotifier posts one status per new bundle, with the cover image
+// attached.
+type MastodonNotifier struct {
+	cfg      MastodonConfig
+	site     Site
+	template *template.Template
+	client   *http.Client
+	sleep    func(time.Duration)
+}
+
+// NewMastodonNotifier validates cfg, parses the status template and
+// resolves the access token from the environment.
+func NewMastodonNotifier(cfg MastodonConfig) (*MastodonNotifier, error) {
+	if cfg.Server == "" {
+		return nil, fmt.Errorf("mastodon: server is required")
+	}
+	cfg.Server = strings.TrimRight(cfg.Server, "/")
+	if cfg.AccessToken == "" {
+		cfg.AccessToken = os.Getenv("MASTODON_ACCESS_TOKEN")
+	}
+	if cfg.AccessToken == "" {
+		return nil, fmt.Errorf("mastodon: access_token or MASTODON_ACCESS_TOKEN is required")
+	}
+	if cfg.Template == "" {
+		cfg.Template = defaultMastodonTemplate
+	}
+	if cfg.Visibility == "" {
+		cfg.Visibility = "public"
+	}
+	if cfg.StateFile == "" {
+		cfg.StateFile = defaultMastodonStateFile
+	}
+	tmpl, err := template.New("mastodon").Parse(cfg.Template)
+	if err == nil {
+		// Catch unknown fields now rather than on the first new bundle.
+		err = tmpl.Execute(io.Discard, mastodonStatus{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mastodon: invalid template: %w", err)
+	}
+	return &MastodonNotifier{
+		cfg:      cfg,
+		template: tmpl,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		sleep:    time.Sleep,
+	}, nil
+}
+
+// Name implements Notifier.
+func (m *MastodonNotifier) Name() string { return "mastodon" }
+
+// Notify implements Notifier. Each posted GUID is recorded right away, so
+// a rerun after a partial failure never posts a bundle twice.
+func (m *MastodonNotifier) Notify(events Events) error {
+	if len(events.New) == 0 {
+		return nil
+	}
+	posted, err := m.loadPosted()
+	if err != nil {
+		return err
+	}
+	limit := m.characterLimit()
+
+	for _, b := range events.New {
+		guid := bundleGUID(b)
+		if posted[guid] {
+			slog.Debug("mastodon: already posted", "guid", guid)
+			continue
+		}
+		text, err := m.statusText(b, limit)
+		if err != nil {
+			return fmt.Errorf("mastodon: %w", err)
+		}
+
+		var mediaIDs []string
+		if b.Image != "" {
+			id, err := m.uploadCover(b)
+			if err != nil {
+				slog.Warn("mastodon: posting without cover image", "guid", guid, "error", err)
+			} else {
+				mediaIDs = append(mediaIDs, id)
+			}
+		}
+		if err := m.postStatus(guid, text, mediaIDs); err != nil {
+			return fmt.Errorf("mastodon: failed to post %s: %w", guid, err)
+		}
+		if err := m.recordPosted(guid); err != nil {
+			return fmt.Errorf("mastodon: %w", err)
+		}
+	}
+	return nil
+}
+
+// characterLimit asks the instance for its status length limit.
+func (m *MastodonNotifier) characterLimit() int {
+	var instance struct {
+		Configuration struct {
+			Statuses struct {
+				MaxCharacters int `json:"max_characters"`
+			} `json:"statuses"`
+		} `json:"configuration"`
+	}
+	resp, err := m.do(http.MethodGet, "/api/v2/instance", "", nil, nil)
+	if err == nil {
+		defer resp.Body.Close()
+		err = json.NewDecoder(resp.Body).Decode(&instance)
+	}
+	if limit := instance.Configuration.Statuses.MaxCharacters; err == nil && limit > 0 {
+		return limit
+	}
+	slog.Debug("mastodon: using default character limit", "error", err)
+	return defaultMastodonLimit
+}
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// statusLength counts like Mastodon: every link counts as 23 characters.
+func statusLength(text string) int {
+	n := utf8.RuneCountInString(text)
+	for _, u := range urlPattern.FindAllString(text, -1) {
+		n += mastodonURLLength - utf8.RuneCountInString(u)
+	}
+	return n
+}
+
+// statusText renders the template for b. If the result exceeds limit, the
+// description is shortened first, then the whole text.
+func (m *MastodonNotifier) statusText(b FanaticalBundle, limit int) (string, error) {
+	data := mastodonStatus{
+		Title:       b.Title,
+		Description: b.Description,
+		Category:    b.Category,
+		Price:       formatAmount(b.Price.Currency, b.Price.Amount),
+		Discount:    b.Price.Discount,
+		Ends:        b.EndDate.UTC().Format("Jan 2, 15:04 MST"),
+		URL:         m.site.bundleURL(b),
+	}
+	if b.Price.Original > 0 {
+		data.Original = formatAmount(b.Price.Currency, b.Price.Original)
+	}
+
+	render := func() (string, error) {
+		var buf bytes.Buffer
+		if err := m.template.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render status: %w", err)
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}
+	text, err := render()
+	if err != nil {
+		return "", err
+	}
+	if over := statusLength(text) - limit; over > 0 && data.Description != "" {
+		keep := utf8.RuneCountInString(data.Description) - over - 1
+		data.Description = ""
+		if keep > 0 {
+			data.Description = truncateRunes(b.Description, keep+1)
+		}
+		if text, err = render(); err != nil {
+			return "", err
+		}
+	}
+	if statusLength(text) > limit {
+		text = truncateRunes(text, limit)
+	}
+	return text, nil
+}
+
+// uploadCover downloads the cover image and uploads it with alt text. It
+// waits for asynchronous processing so the status can reference it.
+func (m *MastodonNotifier) uploadCover(b FanaticalBundle) (string, error) {
+	resp, err := m.client.Get(b.Image)
+	if err != nil {
+		return "", fmt.Errorf("failed to download cover: %w", err)
+	}
+	image, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to download cover: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download cover: status %d", resp.StatusCode)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("description", truncateRunes("Cover art of "+b.Title, 1500))
+	name := path.Base(strings.SplitN(b.Image, "?", 2)[0])
+	fw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename=%q`, name)},
+		"Content-Type":        {imageMIMEType(b.Image)},
+	})
+	if err != nil {
+		return "", err
+	}
+	fw.Write(image)
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	resp, err = m.do(http.MethodPost, "/api/v2/media", mw.FormDataContentType(), body.Bytes(), nil)
+	if err != nil {
+		return "", err
+	}
+	var media struct {
+		ID string `json:"id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&media)
+	resp.Body.Close()
+	if err != nil || media.ID == "" {
+		return "", fmt.Errorf("invalid media response: %v", err)
+	}
+
+	// 202 from the upload means the server is still processing the file.
+	// Polling it answers 206 until processing is done, then 200.
+	processing := resp.StatusCode == http.StatusAccepted
+	for attempt := 1; processing; attempt++ {
+		if attempt > 10 {
+			return "", fmt.Errorf("media %s still processing", media.ID)
+		}
+		m.sleep(time.Second)
+		resp, err = m.do(http.MethodGet, "/api/v1/media/"+media.ID, "", nil, nil)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		processing = resp.StatusCode == http.StatusPartialContent
+	}
+	return media.ID, nil
+}
+
+// postStatus publishes the status. The GUID doubles as Idempotency-Key, so
+// the server drops a duplicate if a retry follows a lost response.
+func (m *MastodonNotifier) postStatus(guid, text string, mediaIDs []string) error {
+	form := url.Values{
+		"status":      {text},
+		"visibility":  {m.cfg.Visibility},
+		"media_ids[]": mediaIDs,
+	}
+	resp, err := m.do(http.MethodPost, "/api/v1/statuses", "application/x-www-form-urlencoded",
+		[]byte(form.Encode()), http.Header{"Idempotency-Key": {guid}})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends an authenticated request, retrying 429 and 5xx responses. Any
+// other non-2xx status is an error.
+func (m *MastodonNotifier) do(method, apiPath, contentType string, body []byte, header http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(method, m.cfg.Server+apiPath, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Authorization", "Bearer "+m.cfg.AccessToken)
+
+		resp, err := m.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, apiPath, err)
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= mastodonAttempts {
+			return nil, fmt.Errorf("%s %s returned status %d: %s", method, apiPath, resp.StatusCode, bytes.TrimSpace(msg))
+		}
+		m.sleep(time.Duration(attempt*5) * time.Second)
+	}
+}
+
+func (m *MastodonNotifier) loadPosted() (map[string]bool, error) {
+	posted := make(map[string]bool)
+	f, err := os.Open(m.cfg.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return posted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mastodon: failed to open state file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if guid := strings.TrimSpace(scanner.Text()); guid != "" {
+			posted[guid] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("mastodon: failed to read state file: %w", err)
+	}
+	return posted, nil
+}
+
+func (m *MastodonNotifier) recordPosted(guid string) error {
+	if err := os.MkdirAll(filepath.Dir(m.cfg.StateFile), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	f, err := os.OpenFile(m.cfg.StateFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open state file: %w", err)
+	}
+	if _, err := fmt.Fprintln(f, guid); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to record %s: %w", guid, err)
+	}
+	return f.Close()
+}
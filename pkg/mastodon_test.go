@@ -0,0 +1,214 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeMastodon is an in-memory Mastodon API serving the endpoints the
+// notifier uses, plus a cover image.
+type fakeMastodon struct {
+	*httptest.Server
+	maxCharacters int
+
+	statuses  []fakeStatus
+	media     map[string]fakeMedia
+	mediaGets int
+}
+
+type fakeStatus struct {
+	Text           string
+	MediaIDs       []string
+	Visibility     string
+	IdempotencyKey string
+}
+
+type fakeMedia struct {
+	Description string
+	ContentType string
+	Size        int
+	// Polls counts the GETs; the first fakeMediaProcessingPolls answer
+	// 206 like a server that is still processing the file.
+	Polls int
+}
+
+const fakeMediaProcessingPolls = 2
+
+func newFakeMastodon(t *testing.T, maxCharacters int) *fakeMastodon {
+	t.Helper()
+	f := &fakeMastodon{maxCharacters: maxCharacters, media: map[string]fakeMedia{}}
+	mux := http.NewServeMux()
+	auth := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, `{"error": "The access token is invalid"}`, http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /api/v2/instance", auth(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"configuration": {"statuses": {"max_characters": %d}}}`, f.maxCharacters)
+	}))
+	mux.HandleFunc("POST /api/v2/media", auth(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		id := fmt.Sprintf("m%d", len(f.media)+1)
+		f.media[id] = fakeMedia{
+			Description: r.FormValue("description"),
+			ContentType: header.Header.Get("Content-Type"),
+			Size:        len(data),
+		}
+		// Pretend the file needs processing.
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"id": %q, "url": null}`, id)
+	}))
+	mux.HandleFunc("GET /api/v1/media/{id}", auth(func(w http.ResponseWriter, r *http.Request) {
+		f.mediaGets++
+		id := r.PathValue("id")
+		media := f.media[id]
+		media.Polls++
+		f.media[id] = media
+		if media.Polls <= fakeMediaProcessingPolls {
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprintf(w, `{"id": %q, "url": null}`, id)
+			return
+		}
+		fmt.Fprintf(w, `{"id": %q, "url": "https://files.example/x.jpg"}`, id)
+	}))
+	mux.HandleFunc("POST /api/v1/statuses", auth(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		for _, id := range r.Form["media_ids[]"] {
+			media, ok := f.media[id]
+			if !ok {
+				http.Error(w, `{"error": "unknown media"}`, http.StatusUnprocessableEntity)
+				return
+			}
+			if media.Polls <= fakeMediaProcessingPolls {
+				http.Error(w, `{"error": "media still processing"}`, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		f.statuses = append(f.statuses, fakeStatus{
+			Text:           r.FormValue("status"),
+			MediaIDs:       r.Form["media_ids[]"],
+			Visibility:     r.FormValue("visibility"),
+			IdempotencyKey: r.Header.Get("Idempotency-Key"),
+		})
+		json.NewEncoder(w).Encode(map[string]string{"id": fmt.Sprint(len(f.statuses))})
+	}))
+	mux.HandleFunc("GET /covers/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\x89PNG fake image"))
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestMastodonNotifierPostsWithCoverAndDeduplicates(t *testing.T) {
+	api := newFakeMastodon(t, 500)
+	t.Setenv("MASTODON_ACCESS_TOKEN", "token")
+	state := filepath.Join(t.TempDir(), "posted.txt")
+
+	n, err := NewMastodonNotifier(MastodonConfig{Server: api.URL + "/", StateFile: state, Visibility: "unlisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.sleep = func(time.Duration) {}
+
+	b := testBundle("deal", time.Unix(1000, 0))
+	b.Description = "Ten great games."
+	b.Image = api.URL + "/covers/deal.png?w=600"
+	b.EndDate = time.Date(2030, time.March, 1, 18, 0, 0, 0, time.UTC)
+	events := Events{New: []FanaticalBundle{b}}
+
+	if err := n.Notify(events); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(api.statuses) != 1 {
+		t.Fatalf("posted %d statuses, want 1", len(api.statuses))
+	}
+	status := api.statuses[0]
+	want := "New on Fanatical: Bundle deal\n$4.99 (was $9.99, -50%) · ends Mar 1, 18:00 UTC\n\nTen great games.\n\nhttps://www.fanatical.com/en/bundle/deal"
+	if status.Text != want {
+		t.Errorf("status text:\n%s\nwant:\n%s", status.Text, want)
+	}
+	if status.Visibility != "unlisted" || status.IdempotencyKey != "fanatical-deal-1000" {
+		t.Errorf("visibility=%q idempotency key=%q", status.Visibility, status.IdempotencyKey)
+	}
+	if len(status.MediaIDs) != 1 || api.mediaGets != fakeMediaProcessingPolls+1 {
+		t.Fatalf("status media %v, %d processing polls", status.MediaIDs, api.mediaGets)
+	}
+	media := api.media[status.MediaIDs[0]]
+	if media.Description != "Cover art of Bundle deal" || media.ContentType != "image/png" || media.Size == 0 {
+		t.Errorf("media = %+v", media)
+	}
+
+	// A rerun with the same events must not post again.
+	n, _ = NewMastodonNotifier(MastodonConfig{Server: api.URL, StateFile: state})
+	if err := n.Notify(events); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.statuses) != 1 {
+		t.Errorf("rerun double-posted: %d statuses", len(api.statuses))
+	}
+	if data, _ := os.ReadFile(state); string(data) != "fanatical-deal-1000\n" {
+		t.Errorf("state file = %q", data)
+	}
+}
+
+func TestMastodonNotifierRespectsCharacterLimit(t *testing.T) {
+	api := newFakeMastodon(t, 120)
+	n, err := NewMastodonNotifier(MastodonConfig{
+		Server:      api.URL,
+		AccessToken: "token",
+		StateFile:   filepath.Join(t.TempDir(), "posted.txt"),
+		Template:    "{{.Title}} for {{.Price}}\n\n{{.Description}}\n\n{{.URL}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := testBundle("long", time.Unix(1000, 0))
+	b.Description = strings.Repeat("Lots of games. ", 20)
+	if err := n.Notify(Events{New: []FanaticalBundle{b}}); err != nil {
+		t.Fatal(err)
+	}
+
+	text := api.statuses[0].Text
+	if got := statusLength(text); got > 120 {
+		t.Errorf("status counts %d characters, over the limit of 120:\n%s", got, text)
+	}
+	if !strings.HasSuffix(text, "https://www.fanatical.com/en/bundle/long") {
+		t.Errorf("link was cut off:\n%s", text)
+	}
+	if !strings.Contains(text, "…") {
+		t.Errorf("description was not shortened:\n%s", text)
+	}
+}
+
+func TestStatusLengthCountsLinksAs23(t *testing.T) {
+	if got := statusLength("Deal: https://www.fanatical.com/en/bundle/some-very-long-slug-indeed"); got != 6+23 {
+		t.Errorf("statusLength = %d, want 29", got)
+	}
+}
+
+func TestNewMastodonNotifierRejectsBadTemplate(t *testing.T) {
+	for _, tmpl := range []string{"{{.Title", "{{.Name}}"} {
+		if _, err := NewMastodonNotifier(MastodonConfig{Server: "https://x", AccessToken: "t", Template: tmpl}); err == nil {
+			t.Errorf("expected an error for template %q", tmpl)
+		}
+	}
+}
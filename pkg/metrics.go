@@ -0,0 +1,244 @@
+package gofanatical
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A small Prometheus registry; the client library would be the only
+// other dependency besides gorilla/feeds. Metrics are package-level like
+// the slog logger, so the fetch and conversion helpers can record without
+// threading state through every call.
+
+type metricKind string
+
+const (
+	metricCounter   metricKind = "counter"
+	metricGauge     metricKind = "gauge"
+	metricHistogram metricKind = "histogram"
+)
+
+// metric is one metric family with optional labels.
+type metric struct {
+	name    string
+	help    string
+	kind    metricKind
+	labels  []string
+	buckets []float64 // histograms only, ascending
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labelValues []string
+	value       float64  // counter and gauge
+	counts      []uint64 // histogram, one per bucket (not cumulative)
+	sum         float64
+	count       uint64
+}
+
+var metricsRegistry []*metric
+
+func newMetric(kind metricKind, name, help string, labels ...string) *metric {
+	m := &metric{name: name, help: help, kind: kind, labels: labels, series: map[string]*metricSeries{}}
+	metricsRegistry = append(metricsRegistry, m)
+	return m
+}
+
+func newHistogram(name, help string, buckets []float64, labels ...string) *metric {
+	m := newMetric(metricHistogram, name, help, labels...)
+	m.buckets = buckets
+	return m
+}
+
+var (
+	metricFetchAttempts = newMetric(metricCounter, "gofanatical_fetch_attempts_total",
+		"Requests made to the bundles API.")
+	metricFetchResponses = newMetric(metricCounter, "gofanatical_fetch_responses_total",
+		"Bundles API responses by HTTP status code; \"error\" when no response arrived.", "code")
+	metricFetchDuration = newHistogram("gofanatical_fetch_duration_seconds",
+		"Bundles API request latency, including reading the body.",
+		[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30})
+	metricBundlesFetched = newMetric(metricCounter, "gofanatical_bundles_fetched_total",
+		"Bundles returned by the API.")
+	metricBundlesKept = newMetric(metricCounter, "gofanatical_bundles_kept_total",
+		"Bundles kept after conversion.")
+	metricBundlesSkipped = newMetric(metricCounter, "gofanatical_bundles_skipped_total",
+		"Bundles dropped during conversion, by reason.", "reason")
+	metricFeedItems = newMetric(metricGauge, "gofanatical_feed_items",
+		"Items in each feed after the last run.", "feed")
+	metricFeedBytes = newMetric(metricGauge, "gofanatical_feed_bytes",
+		"Size of each rendered feed after the last run.", "feed")
+	metricWriteFailures = newMetric(metricCounter, "gofanatical_write_failures_total",
+		"Feeds or pages that could not be generated or published.", "feed")
+	metricLastRun = newMetric(metricGauge, "gofanatical_last_run_timestamp_seconds",
+		"Unix time of the last run.")
+	metricLastRunSuccess = newMetric(metricGauge, "gofanatical_last_run_success",
+		"1 if the last run finished without errors, else 0.")
+)
+
+func (m *metric) get(labelValues []string) *metricSeries {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metric %s: got %d label values, want %d", m.name, len(labelValues), len(m.labels)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: labelValues}
+		if m.kind == metricHistogram {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// Add increases a counter or gauge.
+func (m *metric) Add(v float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value += v
+}
+
+// Inc adds one.
+func (m *metric) Inc(labelValues ...string) { m.Add(1, labelValues...) }
+
+// Set sets a gauge.
+func (m *metric) Set(v float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.get(labelValues).value = v
+}
+
+// Observe records a histogram sample.
+func (m *metric) Observe(v float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.get(labelValues)
+	for i, upper := range m.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// Reset drops every series, e.g. gauges of feeds that no longer exist.
+func (m *metric) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.series = map[string]*metricSeries{}
+}
+
+// writeMetrics renders every metric in the Prometheus text exposition
+// format, series sorted by label values.
+func writeMetrics(w io.Writer) error {
+	var sb strings.Builder
+	for _, m := range metricsRegistry {
+		m.writeTo(&sb)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (m *metric) writeTo(sb *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(sb, "# HELP %s %s\n", m.name, escapeMetricHelp(m.help))
+	fmt.Fprintf(sb, "# TYPE %s %s\n", m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// Unlabeled metrics are always exposed, starting at zero.
+	if len(m.labels) == 0 && len(keys) == 0 {
+		m.get(nil)
+		keys = []string{""}
+	}
+
+	for _, key := range keys {
+		s := m.series[key]
+		labels := m.labelPairs(s.labelValues)
+		if m.kind != metricHistogram {
+			fmt.Fprintf(sb, "%s%s %s\n", m.name, braces(labels), formatMetricValue(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range m.buckets {
+			cumulative += s.counts[i]
+			le := append(labels, `le="`+formatMetricValue(upper)+`"`)
+			fmt.Fprintf(sb, "%s_bucket%s %d\n", m.name, braces(le), cumulative)
+		}
+		fmt.Fprintf(sb, "%s_bucket%s %d\n", m.name, braces(append(labels, `le="+Inf"`)), s.count)
+		fmt.Fprintf(sb, "%s_sum%s %s\n", m.name, braces(labels), formatMetricValue(s.sum))
+		fmt.Fprintf(sb, "%s_count%s %d\n", m.name, braces(labels), s.count)
+	}
+}
+
+func (m *metric) labelPairs(values []string) []string {
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = m.labels[i] + `="` + escapeLabelValue(v) + `"`
+	}
+	return pairs
+}
+
+func braces(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+func escapeMetricHelp(s string) string { return helpEscaper.Replace(s) }
+
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		// Timestamps and byte counts read better without an exponent.
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// metricsHandler serves the registry to Prometheus.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w)
+}
+
+// writeMetricsFile writes the registry for node_exporter's textfile
+// collector. The file is replaced atomically so a scrape never sees half
+// of it.
+func writeMetricsFile(path string) error {
+	var sb strings.Builder
+	if err := writeMetrics(&sb); err != nil {
+		return err
+	}
+	if _, err := writeFileAtomic(path, []byte(sb.String())); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
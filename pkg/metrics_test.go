@@ -0,0 +1,105 @@
+package gofanatical
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricExpositionFormat(t *testing.T) {
+	counter := &metric{name: "test_total", help: "A counter.", kind: metricCounter,
+		labels: []string{"code"}, series: map[string]*metricSeries{}}
+	counter.Inc("500")
+	counter.Add(2, "200")
+	counter.Inc(`we"ird\`)
+
+	hist := &metric{name: "test_seconds", help: "A histogram.", kind: metricHistogram,
+		buckets: []float64{0.5, 1}, series: map[string]*metricSeries{}}
+	hist.Observe(0.2)
+	hist.Observe(0.7)
+	hist.Observe(3)
+
+	var sb strings.Builder
+	counter.writeTo(&sb)
+	hist.writeTo(&sb)
+	want := `# HELP test_total A counter.
+# TYPE test_total counter
+test_total{code="200"} 2
+test_total{code="500"} 1
+test_total{code="we\"ird\\"} 1
+# HELP test_seconds A histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.5"} 1
+test_seconds_bucket{le="1"} 2
+test_seconds_bucket{le="+Inf"} 3
+test_seconds_sum 3.9
+test_seconds_count 3
+`
+	if sb.String() != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestRunRecordsMetrics(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for _, m := range metricsRegistry {
+		m.Reset()
+	}
+	stubBundlesAPI(t, fmt.Sprintf(`[
+		{"name": "Kept Bundle", "slug": "kept", "type": "bundle", "on_sale": true,
+		 "price": {"USD": 4.99}, "available_valid_from": 1000, "available_valid_until": %d},
+		{"name": "Expired Bundle", "slug": "expired", "type": "bundle", "on_sale": true,
+		 "price": {"USD": 4.99}, "available_valid_from": 1000, "available_valid_until": 2000},
+		{"name": "", "slug": "unnamed", "type": "bundle", "on_sale": true},
+		{"name": "Off Sale", "slug": "off", "type": "bundle", "on_sale": false}
+	]`, now.Add(72*time.Hour).Unix()))
+	writeConfig(t, `{}`)
+	metricsFile := filepath.Join(t.TempDir(), "gofanatical.prom")
+
+	if _, err := Run(Options{Clock: func() time.Time { return now }, OutDir: t.TempDir(), MetricsFile: metricsFile}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(metricsFile)
+	if err != nil {
+		t.Fatalf("metrics file not written: %v", err)
+	}
+	for _, want := range []string{
+		"gofanatical_fetch_attempts_total 1\n",
+		`gofanatical_fetch_responses_total{code="200"} 1`,
+		"gofanatical_fetch_duration_seconds_count 1\n",
+		"gofanatical_bundles_fetched_total 4\n",
+		"gofanatical_bundles_kept_total 1\n",
+		`gofanatical_bundles_skipped_total{reason="expired"} 1`,
+		`gofanatical_bundles_skipped_total{reason="not_on_sale"} 1`,
+		`gofanatical_bundles_skipped_total{reason="unnamed"} 1`,
+		`gofanatical_feed_items{feed="games"} 1`,
+		`gofanatical_feed_items{feed="books"} 0`,
+		`gofanatical_feed_bytes{feed="games"} `,
+		"# TYPE gofanatical_write_failures_total counter\n",
+		fmt.Sprintf("gofanatical_last_run_timestamp_seconds %d\n", now.Unix()),
+		"gofanatical_last_run_success 1\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("metrics missing %q:\n%s", want, data)
+		}
+	}
+
+	ts := httptest.NewServer((&Server{}).Handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") || string(body) != string(data) {
+		t.Errorf("/metrics served %q:\n%s", resp.Header.Get("Content-Type"), body)
+	}
+}
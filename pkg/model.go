@@ -4,15 +4,18 @@ import "time"
 
 // FanaticalBundle is the internal representation of a Fanatical deal.
 type FanaticalBundle struct {
-	Title       string
-	Slug        string
-	Description string
-	Image       string
-	URL         string
-	Category    string
-	StartDate   time.Time
-	EndDate     time.Time
-	Price       Price
+	Title            string
+	Slug             string
+	Description      string
+	Image            string
+	URL              string
+	Category         string
+	StartDate        time.Time
+	EndDate          time.Time
+	Price            Price
+	OperatingSystems []string
+	DRM              []string
+	Flags            Flags
 }
 
 // Price holds pricing information for a bundle.
@@ -22,3 +25,11 @@ type Price struct {
 	Original float64
 	Discount int
 }
+
+// Flags holds the promotional markers Fanatical attaches to a deal.
+type Flags struct {
+	BestEver  bool
+	FlashSale bool
+	StarDeal  bool
+	Giveaway  bool
+}
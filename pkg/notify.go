@@ -0,0 +1,184 @@
+package gofanatical
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Events describes what a run changed, for notifiers.
+type Events struct {
+	// At is the run time.
+	At time.Time
+	// New holds bundles that appeared in a category feed this run and
+	// were not in it before.
+	New []FanaticalBundle
+	// Changed holds bundles whose feed item changed, e.g. a new price.
+	Changed []FanaticalBundle
+	// Removed lists feed items that are gone; the bundles themselves are
+	// no longer in the snapshot.
+	Removed []DiffItem
+}
+
+// Empty reports whether there is nothing to announce.
+func (e Events) Empty() bool {
+	return len(e.New) == 0 && len(e.Changed) == 0 && len(e.Removed) == 0
+}
+
+// Notifier pushes run events to an external service. Notify is called on
+// every run except dry runs, also when events are empty, so notifiers that
+// batch events can deliver on their own schedule.
+type Notifier interface {
+	Name() string
+	Notify(Events) error
+}
+
+// collectEvents derives the run events from the category feeds. A feed
+// that was published for the first time announces nothing — every item
+// would count as new and flood the channel.
+func collectEvents(results []FeedResult, matched map[string][]FanaticalBundle, bundles []FanaticalBundle, history *History, now time.Time) Events {
+	return collectFeedEvents(results, categories, matched, bundles, history, now)
+}
+
+// collectFeedEvents is collectEvents for the named feeds. matched holds
+// every bundle of each feed before its limits were applied.
+//
+// Limits cut live bundles from a feed and let them back in later, so the
+// feed diff alone cannot tell what is new or gone. A removed item is only
+// reported once its bundle has left the snapshot, and with a history store
+// a bundle is only new in the run that first saw it. loadConfig requires
+// the history store for limited feeds with notifiers; without limits the
+// diff is exact.
+func collectFeedEvents(results []FeedResult, feeds []string, matched map[string][]FanaticalBundle, bundles []FanaticalBundle, history *History, now time.Time) Events {
+	if history != nil && !history.startedBefore(now) {
+		// A store created by this run saw every bundle first; fall back to
+		// the diff rather than announce them all.
+		history = nil
+	}
+	events := Events{At: now}
+	live := make(map[string]bool, len(bundles))
+	for _, b := range bundles {
+		live[bundleGUID(b)] = true
+	}
+	added := make(map[string]bool)
+	changed := make(map[string]bool)
+	for _, fr := range results {
+		if !slices.Contains(feeds, fr.Name) || fr.Diff.Initial {
+			continue
+		}
+		if history != nil {
+			for _, b := range matched[fr.Name] {
+				if rec, ok := history.Record(b.Slug, b.StartDate); ok && rec.FirstSeen.Equal(now) {
+					added[bundleGUID(b)] = true
+				}
+			}
+		} else {
+			for _, item := range fr.Diff.Added {
+				added[item.GUID] = true
+			}
+		}
+		for _, item := range fr.Diff.Changed {
+			changed[item.GUID] = true
+		}
+		for _, item := range fr.Diff.Removed {
+			if !live[item.GUID] {
+				events.Removed = append(events.Removed, item)
+			}
+		}
+	}
+
+	for _, b := range bundles {
+		switch guid := bundleGUID(b); {
+		case added[guid]:
+			events.New = append(events.New, b)
+		case changed[guid]:
+			events.Changed = append(events.Changed, b)
+		}
+	}
+	sortBundlesOldestFirst(events.New)
+	sortBundlesOldestFirst(events.Changed)
+	sortDiffItems(events.Removed)
+	return events
+}
+
+func sortBundlesOldestFirst(bundles []FanaticalBundle) {
+	sort.Slice(bundles, func(i, j int) bool {
+		if !bundles[i].StartDate.Equal(bundles[j].StartDate) {
+			return bundles[i].StartDate.Before(bundles[j].StartDate)
+		}
+		return bundles[i].Slug < bundles[j].Slug
+	})
+}
+
+// notifiedFeeds returns the names of the feeds whose events reach a
+// notifier: the category feeds if any site-wide notifier is configured,
+// and the watch feeds that have alerts.
+func (c Config) notifiedFeeds() []string {
+	var names []string
+	if c.Discord != nil || c.Email != nil || c.Mastodon != nil || c.Webhooks != nil {
+		names = append(names, categories...)
+	}
+	for _, w := range c.Watchlists {
+		if w.Discord != nil || w.Webhooks != nil {
+			names = append(names, w.feedName())
+		}
+	}
+	return names
+}
+
+// configuredNotifiers returns the notifiers enabled in the config.
+func configuredNotifiers(cfg Config) ([]Notifier, error) {
+	var list []Notifier
+	if cfg.Discord != nil {
+		n, err := NewDiscordNotifier(*cfg.Discord)
+		if err != nil {
+			return nil, err
+		}
+		n.site = cfg.Site
+		list = append(list, n)
+	}
+	if cfg.Email != nil {
+		n, err := NewEmailNotifier(*cfg.Email)
+		if err != nil {
+			return nil, err
+		}
+		n.site = cfg.Site
+		list = append(list, n)
+	}
+	if cfg.Mastodon != nil {
+		n, err := NewMastodonNotifier(*cfg.Mastodon)
+		if err != nil {
+			return nil, err
+		}
+		n.site = cfg.Site
+		list = append(list, n)
+	}
+	if cfg.Webhooks != nil {
+		n, err := NewWebhookNotifier(*cfg.Webhooks)
+		if err != nil {
+			return nil, err
+		}
+		n.site = cfg.Site
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+// notifyAll hands the events to every notifier. One failing notifier does
+// not keep the others from running.
+func notifyAll(list []Notifier, events Events) []error {
+	var errs []error
+	for _, n := range list {
+		if err := n.Notify(events); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if events.Empty() {
+			continue
+		}
+		slog.Info("notifications sent", "notifier", n.Name(),
+			"new", len(events.New), "changed", len(events.Changed), "removed", len(events.Removed))
+	}
+	return errs
+}
@@ -0,0 +1,97 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectEventsFromCategoryDiffs(t *testing.T) {
+	fresh := testBundle("fresh", time.Unix(2000, 0))
+	old := testBundle("old", time.Unix(1000, 0))
+	repriced := testBundle("repriced", time.Unix(1500, 0))
+	firstPublish := testBundle("first", time.Unix(3000, 0))
+	gone := DiffItem{GUID: "fanatical-gone-500", Title: "Bundle gone"}
+
+	results := []FeedResult{
+		{Name: "games", Diff: FeedDiff{
+			Added:   []DiffItem{{GUID: bundleGUID(fresh)}},
+			Changed: []DiffItem{{GUID: bundleGUID(repriced)}},
+			Removed: []DiffItem{gone},
+		}},
+		// A brand-new feed must not announce its whole backlog.
+		{Name: "books", Diff: FeedDiff{Initial: true, Added: []DiffItem{{GUID: bundleGUID(firstPublish)}}}},
+		// Non-category feeds overlap with the category feeds; ignore them.
+		{Name: "cheap", Diff: FeedDiff{Added: []DiffItem{{GUID: bundleGUID(old)}}}},
+	}
+
+	events := collectEvents(results, nil, []FanaticalBundle{old, fresh, repriced, firstPublish}, nil, time.Unix(9000, 0))
+	if len(events.New) != 1 || events.New[0].Slug != "fresh" {
+		t.Errorf("New = %+v, want only the fresh bundle", events.New)
+	}
+	if len(events.Changed) != 1 || events.Changed[0].Slug != "repriced" {
+		t.Errorf("Changed = %+v, want only the repriced bundle", events.Changed)
+	}
+	if len(events.Removed) != 1 || events.Removed[0] != gone {
+		t.Errorf("Removed = %+v, want the gone item", events.Removed)
+	}
+}
+
+func TestRunAnnouncesBundlesCutByLimitsOnlyOnce(t *testing.T) {
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg discordMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("bad message: %v", err)
+		}
+		for _, e := range msg.Embeds {
+			posted = append(posted, e.URL)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	bundle := func(slug string, start int) string {
+		return fmt.Sprintf(`{"name": "Bundle %s", "slug": %q, "type": "bundle", "display_type": "game-bundle", "on_sale": true,
+			"price": {"USD": 4.99}, "fullPrice": {"USD": 49.99},
+			"available_valid_from": %d, "available_valid_until": %d}`, slug, slug, start, now.Add(72*time.Hour).Unix())
+	}
+	writeConfig(t, fmt.Sprintf(`{
+		"history_file": %q,
+		"feed_limits": {"games": {"max_items": 2}},
+		"discord": {"webhook_url": %q}
+	}`, filepath.Join(t.TempDir(), "history.jsonl"), server.URL))
+	opts := Options{Clock: func() time.Time { return now }, OutDir: t.TempDir()}
+
+	// a is cut from the games feed by c and let back in once c is gone;
+	// only c is new.
+	for _, snapshot := range [][]string{{bundle("a", 1000), bundle("b", 2000)},
+		{bundle("a", 1000), bundle("b", 2000), bundle("c", 3000)},
+		{bundle("a", 1000), bundle("b", 2000)}} {
+		stubBundlesAPI(t, "["+strings.Join(snapshot, ",")+"]")
+		if _, err := Run(opts); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		now = now.Add(6 * time.Hour)
+	}
+	if len(posted) != 1 || !strings.HasSuffix(posted[0], "/bundle/c") {
+		t.Errorf("posted %v, want only bundle c", posted)
+	}
+}
+
+func TestLoadConfigRequiresHistoryForLimitedNotifiedFeeds(t *testing.T) {
+	writeConfig(t, `{"limits": {"max_items": 10}, "webhooks": {"urls": ["https://example.com/hook"], "secret": "s"}}`)
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "require history_file") {
+		t.Errorf("err = %v, want a history_file error", err)
+	}
+	writeConfig(t, `{"feed_limits": {"cheap": {"max_items": 10}}, "webhooks": {"urls": ["https://example.com/hook"], "secret": "s"}}`)
+	if _, err := loadConfig(); err != nil {
+		t.Errorf("limits on a feed without notifiers: %v", err)
+	}
+}
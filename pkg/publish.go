@@ -0,0 +1,74 @@
+package gofanatical
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Publisher stores generated output files such as feeds. Names are
+// slash-separated paths relative to the output root, e.g. "games.rss".
+type Publisher interface {
+	// Publish stores data under name. It reports false when the stored
+	// content was already identical and nothing was written.
+	Publish(name string, data []byte) (changed bool, err error)
+	// Fetch returns the currently published content of name, or nil if
+	// nothing is published under that name yet.
+	Fetch(name string) ([]byte, error)
+	// Location describes where name is published, for logs and reports.
+	Location(name string) string
+}
+
+// LocalPublisher writes output into a directory on the local filesystem.
+type LocalPublisher struct {
+	Dir string
+}
+
+// Publish atomically replaces the file when its content changed.
+func (p LocalPublisher) Publish(name string, data []byte) (bool, error) {
+	filename := p.Location(name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return false, fmt.Errorf("failed to create output directory %s: %w", filepath.Dir(filename), err)
+	}
+	return writeFileAtomic(filename, data)
+}
+
+// Fetch reads the file, treating a missing file as empty.
+func (p LocalPublisher) Fetch(name string) ([]byte, error) {
+	data, err := os.ReadFile(p.Location(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p.Location(name), err)
+	}
+	return data, nil
+}
+
+// Location returns the file path for name.
+func (p LocalPublisher) Location(name string) string {
+	return filepath.Join(p.Dir, filepath.FromSlash(name))
+}
+
+// contentType returns the Content-Type served for a published file.
+func contentType(name string) string {
+	switch path.Ext(name) {
+	case ".rss":
+		return "application/rss+xml; charset=utf-8"
+	case ".html":
+		return "text/html; charset=utf-8"
+	case ".css":
+		return "text/css; charset=utf-8"
+	case ".json":
+		return "application/json"
+	case ".xml":
+		return "application/xml; charset=utf-8"
+	case ".gz":
+		return "application/gzip"
+	default:
+		return "application/octet-stream"
+	}
+}
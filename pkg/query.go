@@ -0,0 +1,392 @@
+package gofanatical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A query is a small boolean expression evaluated against a bundle, e.g.
+//
+//	category == "games" && price < 5 && discount >= 80 && "linux" in os
+//
+// Supported operators are ||, &&, !, the comparisons == != < <= > >=, and
+// `in`, which tests list membership (os, drm) or substring containment
+// (title, description). String comparisons ignore case. Expressions are
+// type-checked when compiled, so a config typo fails the run up front
+// instead of silently producing an empty feed.
+type query func(FanaticalBundle) bool
+
+type valueKind int
+
+const (
+	kindBool valueKind = iota
+	kindNumber
+	kindString
+	kindList
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindBool:
+		return "bool"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	default:
+		return "list"
+	}
+}
+
+// operand is a compiled, typed sub-expression. Exactly one of the
+// function fields is set, matching kind.
+type operand struct {
+	kind   valueKind
+	boolFn func(FanaticalBundle) bool
+	numFn  func(FanaticalBundle) float64
+	strFn  func(FanaticalBundle) string
+	listFn func(FanaticalBundle) []string
+}
+
+// queryFields lists the bundle attributes a query can reference.
+var queryFields = map[string]operand{
+	"title":       {kind: kindString, strFn: func(b FanaticalBundle) string { return b.Title }},
+	"slug":        {kind: kindString, strFn: func(b FanaticalBundle) string { return b.Slug }},
+	"description": {kind: kindString, strFn: func(b FanaticalBundle) string { return b.Description }},
+	"category":    {kind: kindString, strFn: func(b FanaticalBundle) string { return b.Category }},
+	"currency":    {kind: kindString, strFn: func(b FanaticalBundle) string { return b.Price.Currency }},
+	"price":       {kind: kindNumber, numFn: func(b FanaticalBundle) float64 { return b.Price.Amount }},
+	"original":    {kind: kindNumber, numFn: func(b FanaticalBundle) float64 { return b.Price.Original }},
+	"discount":    {kind: kindNumber, numFn: func(b FanaticalBundle) float64 { return float64(b.Price.Discount) }},
+	"os":          {kind: kindList, listFn: func(b FanaticalBundle) []string { return b.OperatingSystems }},
+	"drm":         {kind: kindList, listFn: func(b FanaticalBundle) []string { return b.DRM }},
+	"best_ever":   {kind: kindBool, boolFn: func(b FanaticalBundle) bool { return b.Flags.BestEver }},
+	"flash_sale":  {kind: kindBool, boolFn: func(b FanaticalBundle) bool { return b.Flags.FlashSale }},
+	"star_deal":   {kind: kindBool, boolFn: func(b FanaticalBundle) bool { return b.Flags.StarDeal }},
+	"giveaway":    {kind: kindBool, boolFn: func(b FanaticalBundle) bool { return b.Flags.Giveaway }},
+}
+
+// compileQuery parses and type-checks expr. The result must be boolean.
+func compileQuery(expr string) (query, error) {
+	tokens, err := tokenizeQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	op, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+	}
+	if op.kind != kindBool {
+		return nil, fmt.Errorf("query must be a boolean expression, got %s", op.kind)
+	}
+	return op.boolFn, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// queryStringEscaper escapes like tokenizeQuery unescapes: a backslash
+// makes the next byte literal.
+var queryStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// quoteQueryString returns s as a query string literal. Unlike %q it
+// writes no Go escape sequences, which the tokenizer does not know.
+func quoteQueryString(s string) string {
+	return `"` + queryStringEscaper.Replace(s) + `"`
+}
+
+func tokenizeQuery(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(expr) && expr[i] != '"' {
+				if expr[i] == '\\' && i+1 < len(expr) {
+					i++
+				}
+				sb.WriteByte(expr[i])
+				i++
+			}
+			if i >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			tokens = append(tokens, token{tokString, sb.String(), start})
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(expr) && (expr[i] >= '0' && expr[i] <= '9' || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, expr[start:i], start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(expr) && (expr[i] == '_' || unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, expr[start:i], start})
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "end of query", len(expr)}), nil
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token { return p.tokens[p.pos] }
+
+func (p *queryParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (operand, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return operand{}, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		tok := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return operand{}, err
+		}
+		if left.kind != kindBool || right.kind != kindBool {
+			return operand{}, fmt.Errorf("|| at offset %d needs boolean operands", tok.pos)
+		}
+		l, r := left.boolFn, right.boolFn
+		left = operand{kind: kindBool, boolFn: func(b FanaticalBundle) bool { return l(b) || r(b) }}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (operand, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return operand{}, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		tok := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return operand{}, err
+		}
+		if left.kind != kindBool || right.kind != kindBool {
+			return operand{}, fmt.Errorf("&& at offset %d needs boolean operands", tok.pos)
+		}
+		l, r := left.boolFn, right.boolFn
+		left = operand{kind: kindBool, boolFn: func(b FanaticalBundle) bool { return l(b) && r(b) }}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (operand, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return operand{}, err
+		}
+		if inner.kind != kindBool {
+			return operand{}, fmt.Errorf("! at offset %d needs a boolean operand", tok.pos)
+		}
+		fn := inner.boolFn
+		return operand{kind: kindBool, boolFn: func(b FanaticalBundle) bool { return !fn(b) }}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (operand, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return operand{}, err
+	}
+
+	tok := p.peek()
+	isComparison := tok.kind == tokOp && strings.ContainsAny(tok.text, "=<>") && tok.text != "!"
+	isIn := tok.kind == tokIdent && tok.text == "in"
+	if !isComparison && !isIn {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return operand{}, err
+	}
+	if isIn {
+		return compileIn(left, right, tok)
+	}
+	return compileComparison(left, right, tok)
+}
+
+func (p *queryParser) parsePrimary() (operand, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return operand{}, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return operand{}, fmt.Errorf("expected ) at offset %d, got %q", closing.pos, closing.text)
+		}
+		return inner, nil
+	case tokString:
+		s := tok.text
+		return operand{kind: kindString, strFn: func(FanaticalBundle) string { return s }}, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number %q at offset %d", tok.text, tok.pos)
+		}
+		return operand{kind: kindNumber, numFn: func(FanaticalBundle) float64 { return n }}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			v := tok.text == "true"
+			return operand{kind: kindBool, boolFn: func(FanaticalBundle) bool { return v }}, nil
+		}
+		field, ok := queryFields[tok.text]
+		if !ok {
+			return operand{}, fmt.Errorf("unknown field %q at offset %d", tok.text, tok.pos)
+		}
+		return field, nil
+	}
+	return operand{}, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+}
+
+func compileComparison(left, right operand, tok token) (operand, error) {
+	if left.kind != right.kind {
+		return operand{}, fmt.Errorf("%s at offset %d compares %s with %s", tok.text, tok.pos, left.kind, right.kind)
+	}
+
+	var cmp func(FanaticalBundle) int
+	switch left.kind {
+	case kindNumber:
+		l, r := left.numFn, right.numFn
+		cmp = func(b FanaticalBundle) int {
+			lv, rv := l(b), r(b)
+			switch {
+			case lv < rv:
+				return -1
+			case lv > rv:
+				return 1
+			}
+			return 0
+		}
+	case kindString:
+		l, r := left.strFn, right.strFn
+		cmp = func(b FanaticalBundle) int {
+			return strings.Compare(strings.ToLower(l(b)), strings.ToLower(r(b)))
+		}
+	case kindBool:
+		if tok.text != "==" && tok.text != "!=" {
+			return operand{}, fmt.Errorf("%s at offset %d cannot order booleans", tok.text, tok.pos)
+		}
+		l, r := left.boolFn, right.boolFn
+		cmp = func(b FanaticalBundle) int {
+			if l(b) == r(b) {
+				return 0
+			}
+			return 1
+		}
+	default:
+		return operand{}, fmt.Errorf("%s at offset %d cannot compare lists; use in", tok.text, tok.pos)
+	}
+
+	var test func(int) bool
+	switch tok.text {
+	case "==":
+		test = func(c int) bool { return c == 0 }
+	case "!=":
+		test = func(c int) bool { return c != 0 }
+	case "<":
+		test = func(c int) bool { return c < 0 }
+	case "<=":
+		test = func(c int) bool { return c <= 0 }
+	case ">":
+		test = func(c int) bool { return c > 0 }
+	case ">=":
+		test = func(c int) bool { return c >= 0 }
+	default:
+		return operand{}, fmt.Errorf("unknown operator %q at offset %d", tok.text, tok.pos)
+	}
+	return operand{kind: kindBool, boolFn: func(b FanaticalBundle) bool { return test(cmp(b)) }}, nil
+}
+
+func compileIn(left, right operand, tok token) (operand, error) {
+	if left.kind != kindString {
+		return operand{}, fmt.Errorf("in at offset %d needs a string on the left, got %s", tok.pos, left.kind)
+	}
+	needle := left.strFn
+	switch right.kind {
+	case kindList:
+		list := right.listFn
+		return operand{kind: kindBool, boolFn: func(b FanaticalBundle) bool {
+			n := needle(b)
+			for _, v := range list(b) {
+				if strings.EqualFold(v, n) {
+					return true
+				}
+			}
+			return false
+		}}, nil
+	case kindString:
+		haystack := right.strFn
+		return operand{kind: kindBool, boolFn: func(b FanaticalBundle) bool {
+			return strings.Contains(strings.ToLower(haystack(b)), strings.ToLower(needle(b)))
+		}}, nil
+	}
+	return operand{}, fmt.Errorf("in at offset %d needs a list or string on the right, got %s", tok.pos, right.kind)
+}
@@ -0,0 +1,77 @@
+package gofanatical
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompileQueryMatches(t *testing.T) {
+	linuxGame := testBundle("linux-game", time.Unix(1000, 0))
+	linuxGame.Category = "games"
+	linuxGame.Price = Price{Currency: "USD", Amount: 2.99, Original: 29.99, Discount: 90}
+	linuxGame.OperatingSystems = []string{"windows", "Linux"}
+	linuxGame.DRM = []string{"steam"}
+	linuxGame.Flags.StarDeal = true
+
+	windowsGame := linuxGame
+	windowsGame.OperatingSystems = []string{"windows"}
+
+	tests := []struct {
+		expr string
+		want map[string]bool
+	}{
+		{`category == "games" && price < 5 && discount >= 80 && "linux" in os`, map[string]bool{"linux": true, "windows": false}},
+		{`!("linux" in os)`, map[string]bool{"linux": false, "windows": true}},
+		{`category == "GAMES"`, map[string]bool{"linux": true, "windows": true}},
+		{`"bundle linux" in title`, map[string]bool{"linux": true, "windows": true}},
+		{`price > 5 || star_deal`, map[string]bool{"linux": true, "windows": true}},
+		{`star_deal == false`, map[string]bool{"linux": false, "windows": false}},
+		{`"gog" in drm || original != 29.99`, map[string]bool{"linux": false, "windows": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := compileQuery(tt.expr)
+			if err != nil {
+				t.Fatalf("compileQuery failed: %v", err)
+			}
+			if got := q(linuxGame); got != tt.want["linux"] {
+				t.Errorf("linux bundle: got %v, want %v", got, tt.want["linux"])
+			}
+			if got := q(windowsGame); got != tt.want["windows"] {
+				t.Errorf("windows bundle: got %v, want %v", got, tt.want["windows"])
+			}
+		})
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`price`,
+		`prize < 5`,
+		`price < "five"`,
+		`category == "games" &&`,
+		`(price < 5`,
+		`"unterminated`,
+		`os == "linux"`,
+		`5 in os`,
+		`price < 5 discount`,
+		`price # 5`,
+	}
+
+	for _, expr := range tests {
+		if _, err := compileQuery(expr); err == nil {
+			t.Errorf("compileQuery(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestQuoteQueryStringRoundTrips(t *testing.T) {
+	for _, s := range []string{"plain", "tab\there", `say "hi"`, `back\slash`, "Pokémon\n", `\"`} {
+		tokens, err := tokenizeQuery(quoteQueryString(s))
+		if err != nil || len(tokens) != 2 || tokens[0].kind != tokString || tokens[0].text != s {
+			t.Errorf("quoteQueryString(%q) tokenized as %+v (err %v)", s, tokens, err)
+		}
+	}
+}
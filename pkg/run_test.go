@@ -12,12 +12,30 @@ import (
 	"time"
 )
 
+// stubBundlesAPI points bundlesURL at a server returning body.
+func stubBundlesAPI(t *testing.T, body string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	oldURL := bundlesURL
+	bundlesURL = server.URL
+	t.Cleanup(func() { bundlesURL = oldURL })
+}
+
 // TestRunEndToEnd drives the full pipeline against a stub API server:
-// fetch → categorize → dedup → write docs/*.rss. It also runs the pipeline
-// twice to guarantee unchanged input produces byte-identical files — the
-// property the CI workflow relies on to only commit real changes.
+// fetch → categorize → dedup → write docs/*.rss, including a user-defined
+// feed. It also runs the pipeline twice to guarantee unchanged input
+// produces byte-identical files — the property the CI workflow relies on
+// to only commit real changes.
 func TestRunEndToEnd(t *testing.T) {
-	future := time.Now().Add(72 * time.Hour).Unix()
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	out := t.TempDir()
+	opts := Options{Clock: func() time.Time { return now }, OutDir: out}
+	future := now.Add(72 * time.Hour).Unix()
 	body := fmt.Sprintf(`[
 		{"name": "Killer Bundle 42", "slug": "killer-42", "type": "bundle", "display_type": "bundle",
 		 "on_sale": true, "price": {"USD": 4.99}, "fullPrice": {"USD": 49.99},
@@ -33,32 +51,22 @@ func TestRunEndToEnd(t *testing.T) {
 		 "available_valid_from": 1000, "available_valid_until": %d}
 	]`, future, future, future, future)
 
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprint(w, body)
-	}))
-	defer server.Close()
-
-	oldURL := bundlesURL
-	bundlesURL = server.URL
-	defer func() { bundlesURL = oldURL }()
+	stubBundlesAPI(t, body)
+	writeConfig(t, `{"feeds": [{"name": "cheap", "title": "Cheap Deals", "query": "price < 10"}]}`)
 
-	oldWD, err := os.Getwd()
+	result, err := Run(opts)
 	if err != nil {
-		t.Fatal(err)
-	}
-	if err := os.Chdir(t.TempDir()); err != nil {
-		t.Fatal(err)
-	}
-	defer os.Chdir(oldWD)
-
-	if err := Run(); err != nil {
 		t.Fatalf("Run failed: %v", err)
 	}
+	for _, fr := range result.Feeds {
+		if !fr.Changed {
+			t.Errorf("first run reported %s as unchanged", fr.Name)
+		}
+	}
 
 	firstRun := map[string]string{}
-	for _, category := range []string{"books", "games", "software"} {
-		path := filepath.Join("docs", category+".rss")
+	for _, category := range []string{"books", "games", "software", "ending-soon"} {
+		path := filepath.Join(out, category+".rss")
 		data, err := os.ReadFile(path)
 		if err != nil {
 			t.Fatalf("missing feed file %s: %v", path, err)
@@ -85,12 +93,49 @@ func TestRunEndToEnd(t *testing.T) {
 		t.Error("book bundle leaked into games feed")
 	}
 
+	// The user-defined feed spans categories but applies its own filter.
+	cheap, err := os.ReadFile(filepath.Join(out, "cheap.rss"))
+	if err != nil {
+		t.Fatalf("missing custom feed: %v", err)
+	}
+	if !strings.Contains(string(cheap), "Killer Bundle 42") || !strings.Contains(string(cheap), "Fantasy Book Library") {
+		t.Error("custom feed missing bundles priced under 10")
+	}
+	if strings.Contains(string(cheap), "Excel Toolkit") {
+		t.Error("custom feed contains a bundle priced over 10")
+	}
+
+	index, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatalf("missing index.html: %v", err)
+	}
+	firstRun["index"] = string(index)
+	for _, want := range []string{`href="games.rss"`, `href="ending-soon.rss"`, `href="cheap.rss"`, "Cheap Deals"} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.html missing %q", want)
+		}
+	}
+
 	// Second run with identical input must produce byte-identical files.
-	if err := Run(); err != nil {
+	result, err = Run(opts)
+	if err != nil {
 		t.Fatalf("second Run failed: %v", err)
 	}
+	// Every file checked above except index.html, plus the custom feed.
+	if len(result.Feeds) != len(firstRun) {
+		t.Errorf("second run reported %d feeds, want %d", len(result.Feeds), len(firstRun))
+	}
+	for _, fr := range result.Feeds {
+		if fr.Changed {
+			t.Errorf("second run rewrote unchanged feed %s", fr.Name)
+		}
+	}
 	for category, before := range firstRun {
-		after, err := os.ReadFile(filepath.Join("docs", category+".rss"))
+		file := category + ".rss"
+		if category == "index" {
+			file = "index.html"
+		}
+		after, err := os.ReadFile(filepath.Join(out, file))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -99,3 +144,104 @@ func TestRunEndToEnd(t *testing.T) {
 		}
 	}
 }
+
+// TestRunUsesInjectedClock pins the clock so expiry filtering and the
+// ending-soon bucket are decided by the injected instant, not the wall
+// clock.
+func TestRunUsesInjectedClock(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	body := fmt.Sprintf(`[
+		{"name": "Last Call Bundle", "slug": "last-call", "type": "bundle",
+		 "on_sale": true, "price": {"USD": 1.99}, "fullPrice": {"USD": 19.99},
+		 "available_valid_from": 1000, "available_valid_until": %d},
+		{"name": "Long Runner Bundle", "slug": "long-runner", "type": "bundle",
+		 "on_sale": true, "price": {"USD": 1.99}, "fullPrice": {"USD": 19.99},
+		 "available_valid_from": 1000, "available_valid_until": %d},
+		{"name": "Already Over Bundle", "slug": "already-over", "type": "bundle",
+		 "on_sale": true, "price": {"USD": 1.99}, "fullPrice": {"USD": 19.99},
+		 "available_valid_from": 1000, "available_valid_until": %d}
+	]`, now.Add(24*time.Hour).Unix(), now.Add(10*24*time.Hour).Unix(), now.Add(-time.Hour).Unix())
+
+	stubBundlesAPI(t, body)
+	writeConfig(t, `{}`)
+	out := t.TempDir()
+
+	if _, err := Run(Options{Clock: func() time.Time { return now }, OutDir: out}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	games, err := os.ReadFile(filepath.Join(out, "games.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(games), "Already Over Bundle") {
+		t.Error("bundle expired at the injected time was kept")
+	}
+	if !strings.Contains(string(games), "Long Runner Bundle") {
+		t.Error("bundle live at the injected time was dropped")
+	}
+
+	endingSoon, err := os.ReadFile(filepath.Join(out, "ending-soon.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(endingSoon), "Last Call Bundle") || strings.Contains(string(endingSoon), "Long Runner Bundle") {
+		t.Errorf("ending-soon feed not computed from the injected clock:\n%s", endingSoon)
+	}
+}
+
+func TestRunDryRunWritesNothing(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	bundle := func(slug, name string, price float64) string {
+		return fmt.Sprintf(`{"name": %q, "slug": %q, "type": "bundle", "on_sale": true,
+			"price": {"USD": %v}, "fullPrice": {"USD": 49.99},
+			"available_valid_from": 1000, "available_valid_until": %d}`, name, slug, price, now.Add(72*time.Hour).Unix())
+	}
+
+	out := t.TempDir()
+	history := filepath.Join(t.TempDir(), "history.jsonl")
+	writeConfig(t, fmt.Sprintf(`{"history_file": %q}`, history))
+	opts := Options{Clock: func() time.Time { return now }, OutDir: out}
+
+	stubBundlesAPI(t, "["+bundle("kept", "Kept Bundle", 4.99)+","+bundle("gone", "Gone Bundle", 4.99)+"]")
+	if _, err := Run(opts); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	gamesPath := filepath.Join(out, "games.rss")
+	before, err := os.ReadFile(gamesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	historyBefore, err := os.ReadFile(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stubBundlesAPI(t, "["+bundle("kept", "Kept Bundle", 1.99)+","+bundle("new", "New Bundle", 4.99)+"]")
+	opts.DryRun = true
+	result, err := Run(opts)
+	if err != nil {
+		t.Fatalf("dry Run failed: %v", err)
+	}
+
+	if after, _ := os.ReadFile(gamesPath); string(after) != string(before) {
+		t.Error("dry run modified games.rss")
+	}
+	if after, _ := os.ReadFile(history); string(after) != string(historyBefore) {
+		t.Error("dry run appended to the history log")
+	}
+
+	var summary strings.Builder
+	result.WriteDiffSummary(&summary)
+	for _, want := range []string{
+		filepath.Join(out, "games.rss") + ": 1 added, 1 removed, 1 changed",
+		"  + New Bundle",
+		"  - Gone Bundle",
+		"  ~ Kept Bundle",
+		filepath.Join(out, "books.rss") + ": 0 added, 0 removed, 0 changed",
+	} {
+		if !strings.Contains(summary.String(), want) {
+			t.Errorf("summary missing %q:\n%s", want, summary.String())
+		}
+	}
+}
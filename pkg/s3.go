@@ -0,0 +1,238 @@
+package gofanatical
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures publishing to an S3-compatible object store (AWS
+// S3, MinIO, R2, ...). Credentials come from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY so they never end up in the config file.
+type S3Config struct {
+	// Endpoint is the base URL, e.g. "https://s3.eu-central-1.amazonaws.com"
+	// or "http://minio.internal:9000". Requests use path-style addressing.
+	Endpoint string `json:"endpoint"`
+	Bucket   string `json:"bucket"`
+	Region   string `json:"region"`
+	// Prefix is prepended to every object key, e.g. "feeds/".
+	Prefix string `json:"prefix"`
+	// CacheControl is sent with every object. Empty means
+	// defaultCacheControl.
+	CacheControl string `json:"cache_control"`
+}
+
+// defaultCacheControl matches the update schedule: caches may serve a
+// feed for a while but must not hold it across several runs.
+const defaultCacheControl = "public, max-age=300"
+
+// S3Publisher uploads output files with AWS Signature Version 4.
+type S3Publisher struct {
+	cfg       S3Config
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3Publisher validates cfg and reads the credentials from the
+// environment.
+func NewS3Publisher(cfg S3Config) (*S3Publisher, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3: endpoint and bucket are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.CacheControl == "" {
+		cfg.CacheControl = defaultCacheControl
+	}
+	p := &S3Publisher{
+		cfg:       cfg,
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		now:       time.Now,
+	}
+	if p.accessKey == "" || p.secretKey == "" {
+		return nil, fmt.Errorf("s3: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return p, nil
+}
+
+// Publish uploads data unless the stored object's ETag already matches
+// its MD5. The object gets a Content-Type from its extension and the
+// configured Cache-Control; Content-MD5 lets the store reject corrupted
+// uploads, and the resulting ETag lets HTTP caches revalidate.
+func (p *S3Publisher) Publish(name string, data []byte) (bool, error) {
+	sum := md5.Sum(data)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	resp, err := p.do(http.MethodHead, name, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") == etag {
+		return false, nil
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType(name))
+	header.Set("Cache-Control", p.cfg.CacheControl)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+
+	resp, err = p.do(http.MethodPut, name, data, header)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("s3: PUT %s returned status %d: %s", p.Location(name), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return true, nil
+}
+
+// Fetch downloads the object, treating 404 as empty.
+func (p *S3Publisher) Fetch(name string) ([]byte, error) {
+	resp, err := p.do(http.MethodGet, name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("s3: failed to read %s: %w", p.Location(name), err)
+		}
+		return data, nil
+	case http.StatusNotFound:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("s3: GET %s returned status %d", p.Location(name), resp.StatusCode)
+}
+
+// Location returns the s3:// URI of name.
+func (p *S3Publisher) Location(name string) string {
+	return "s3://" + p.cfg.Bucket + "/" + p.cfg.Prefix + name
+}
+
+func (p *S3Publisher) do(method, name string, body []byte, header http.Header) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimRight(p.cfg.Endpoint, "/") + "/" + p.cfg.Bucket + "/" + p.cfg.Prefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("s3: invalid object URL: %w", err)
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("s3: failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	signV4(req, body, p.accessKey, p.secretKey, p.cfg.Region, p.now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %s %s: %w", method, p.Location(name), err)
+	}
+	return resp, nil
+}
+
+// signV4 adds AWS Signature Version 4 headers to req. Every header
+// already set on req is signed, plus Host.
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		vals := append([]string(nil), values[key]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsEscape(key)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything except RFC 3986 unreserved
+// characters, as SigV4 requires.
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,268 @@
+package gofanatical
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServeOptions configures Serve.
+type ServeOptions struct {
+	// Run configures each pipeline run. Its Publisher, if set, still
+	// receives every file; the server keeps a copy in memory.
+	Run Options
+	// Addr is the listen address. Empty means ":8080".
+	Addr string
+	// Interval is the time between runs. Zero means defaultServeInterval.
+	Interval time.Duration
+	// Jitter adds a random delay of up to this much to every interval, so
+	// mirrors do not all hit the API at the same moment.
+	Jitter time.Duration
+	// StaleAfter is how old the last successful fetch may get before
+	// /healthz reports unhealthy. Zero means two intervals plus the
+	// jitter, so a single failed run does not trip it.
+	StaleAfter time.Duration
+}
+
+// defaultServeInterval matches the GitHub Actions schedule.
+const defaultServeInterval = 6 * time.Hour
+
+// Server runs the pipeline on a schedule and serves its output over HTTP.
+type Server struct {
+	opts  ServeOptions
+	site  Site
+	files *cachingPublisher
+
+	mu   sync.RWMutex
+	snap *snapshot
+}
+
+// NewServer resolves the publisher from the config and wraps it so the
+// generated files can be served from memory.
+func NewServer(opts ServeOptions) (*Server, error) {
+	if opts.Addr == "" {
+		opts.Addr = ":8080"
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultServeInterval
+	}
+	if opts.StaleAfter <= 0 {
+		opts.StaleAfter = 2*opts.Interval + opts.Jitter
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	publisher, err := resolvePublisher(opts.Run, cfg)
+	if err != nil {
+		return nil, err
+	}
+	files := &cachingPublisher{Publisher: publisher, now: opts.Run.now, files: map[string]cachedFile{}}
+	opts.Run.Publisher = files
+	return &Server{opts: opts, site: cfg.Site, files: files}, nil
+}
+
+// Serve runs the pipeline right away and then every interval, serving the
+// output until ctx is cancelled. A failed run is logged and the previous
+// output stays online.
+func Serve(ctx context.Context, opts ServeOptions) error {
+	s, err := NewServer(opts)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              s.opts.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go s.schedule(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("serving feeds", "addr", s.opts.Addr, "interval", s.opts.Interval, "jitter", s.opts.Jitter)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) schedule(ctx context.Context) {
+	for {
+		s.runOnce()
+		timer := time.NewTimer(s.nextDelay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Server) runOnce() {
+	start := time.Now()
+	result, err := Run(s.opts.Run)
+	s.files.forgetFetched()
+	if result.Bundles != nil {
+		s.setSnapshot(result.Bundles, s.opts.Run.now())
+	}
+	if err != nil {
+		slog.Error("scheduled run failed", "error", err, "duration", time.Since(start))
+		return
+	}
+	slog.Info("scheduled run finished", "feeds", len(result.Feeds), "duration", time.Since(start))
+}
+
+func (s *Server) nextDelay() time.Duration {
+	delay := s.opts.Interval
+	if s.opts.Jitter > 0 {
+		delay += rand.N(s.opts.Jitter)
+	}
+	return delay
+}
+
+// Handler serves the published files: "/" is index.html, everything else
+// maps to the file of the same name. /feed.rss is generated on the fly,
+// /metrics exposes Prometheus metrics, /healthz the staleness check, and
+// /api/ the current bundles as JSON.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(dynamicFeedPath, s.serveDynamicFeed)
+	mux.HandleFunc("/metrics", metricsHandler)
+	mux.HandleFunc("/healthz", s.serveHealth)
+	mux.HandleFunc("GET /api/bundles", s.serveAPIBundles)
+	mux.HandleFunc("GET /api/bundles/{slug}", s.serveAPIBundle)
+	mux.HandleFunc("GET /api/categories", s.serveAPICategories)
+	mux.HandleFunc("/", s.serveFile)
+	return mux
+}
+
+// serveFile answers conditional requests via http.ServeContent, which
+// compares If-None-Match with the ETag and If-Modified-Since with the
+// Last-Modified time.
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+
+	f, err := s.files.file(name)
+	if err != nil {
+		slog.Error("failed to load file", "file", name, "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if f == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType(name))
+	w.Header().Set("ETag", f.etag)
+	w.Header().Set("Cache-Control", defaultCacheControl)
+	http.ServeContent(w, r, name, f.modTime, bytes.NewReader(f.data))
+}
+
+// cachingPublisher passes every call through to the wrapped Publisher and
+// keeps the published content in memory for serving. Files it had to
+// fetch from the wrapped publisher are only kept until the next run, so
+// changes made outside this process show up.
+type cachingPublisher struct {
+	Publisher
+	now func() time.Time
+
+	mu    sync.RWMutex
+	files map[string]cachedFile
+}
+
+type cachedFile struct {
+	data    []byte
+	etag    string
+	modTime time.Time
+	// fetched marks files read from the wrapped publisher rather than
+	// published by this process.
+	fetched bool
+}
+
+// Publish implements Publisher. The modification time only advances when
+// the content changes, so Last-Modified stays stable across runs.
+func (c *cachingPublisher) Publish(name string, data []byte) (bool, error) {
+	changed, err := c.Publisher.Publish(name, data)
+	if err != nil {
+		return changed, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.files[name]; !ok || !bytes.Equal(old.data, data) {
+		c.files[name] = newCachedFile(data, c.now())
+	} else if old.fetched {
+		old.fetched = false
+		c.files[name] = old
+	}
+	return changed, nil
+}
+
+// forgetFetched drops the files read from the wrapped publisher, so the
+// next request reads them again.
+func (c *cachingPublisher) forgetFetched() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maps.DeleteFunc(c.files, func(_ string, f cachedFile) bool { return f.fetched })
+}
+
+// file returns name from memory, falling back to the wrapped publisher
+// for files this process has not published (yet), such as style.css or
+// the previous run's feeds after a restart. It returns nil if the file
+// does not exist.
+func (c *cachingPublisher) file(name string) (*cachedFile, error) {
+	c.mu.RLock()
+	f, ok := c.files[name]
+	c.mu.RUnlock()
+	if ok {
+		return &f, nil
+	}
+
+	data, err := c.Publisher.Fetch(name)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.files[name]; ok {
+		return &f, nil
+	}
+	f = newCachedFile(data, c.now())
+	f.fetched = true
+	c.files[name] = f
+	return &f, nil
+}
+
+func newCachedFile(data []byte, modTime time.Time) cachedFile {
+	sum := sha256.Sum256(data)
+	return cachedFile{
+		data:    data,
+		etag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		modTime: modTime.UTC().Truncate(time.Second),
+	}
+}
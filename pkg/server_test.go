@@ -0,0 +1,103 @@
+package gofanatical
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerServesFeedsWithCacheValidators(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	stubBundlesAPI(t, fmt.Sprintf(`[{"name": "Killer Bundle 42", "slug": "killer-42", "type": "bundle",
+		"on_sale": true, "price": {"USD": 4.99}, "fullPrice": {"USD": 49.99},
+		"available_valid_from": 1000, "available_valid_until": %d}]`, now.Add(72*time.Hour).Unix()))
+	writeConfig(t, `{}`)
+	out := t.TempDir()
+	if err := os.WriteFile(filepath.Join(out, "style.css"), []byte("body {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(ServeOptions{Run: Options{Clock: func() time.Time { return now }, OutDir: out}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.runOnce()
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	get := func(path string, header http.Header) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("/games.rss", nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/rss+xml; charset=utf-8" {
+		t.Fatalf("GET /games.rss: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" || lastModified != "Fri, 01 Mar 2030 12:00:00 GMT" {
+		t.Errorf("ETag=%q Last-Modified=%q", etag, lastModified)
+	}
+
+	if resp := get("/games.rss", http.Header{"If-None-Match": {etag}}); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", resp.StatusCode)
+	}
+	if resp := get("/games.rss", http.Header{"If-Modified-Since": {lastModified}}); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("If-Modified-Since: status %d, want 304", resp.StatusCode)
+	}
+	if resp := get("/", nil); resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("GET /: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if resp := get("/style.css", nil); resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/css") {
+		t.Errorf("GET /style.css: %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if resp := get("/missing.rss", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /missing.rss: %d, want 404", resp.StatusCode)
+	}
+	if resp, err := http.Post(ts.URL+"/games.rss", "text/plain", nil); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST: %v %v", resp, err)
+	}
+
+	// An unchanged rerun later keeps the validators, so clients keep
+	// getting 304s.
+	now = now.Add(time.Hour)
+	s.runOnce()
+	resp = get("/games.rss", nil)
+	if resp.Header.Get("ETag") != etag || resp.Header.Get("Last-Modified") != lastModified {
+		t.Errorf("validators changed without content change: %q %q", resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+	}
+
+	// Files the server did not publish itself are read again after the
+	// next run.
+	cssETag := get("/style.css", nil).Header.Get("ETag")
+	if err := os.WriteFile(filepath.Join(out, "style.css"), []byte("body { margin: 0 }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.runOnce()
+	if resp := get("/style.css", nil); resp.Header.Get("ETag") == cssETag {
+		t.Errorf("style.css changed on disk but is still served with ETag %s", cssETag)
+	}
+}
+
+func TestServerNextDelayAddsJitter(t *testing.T) {
+	s := &Server{opts: ServeOptions{Interval: time.Hour, Jitter: 10 * time.Minute}}
+	for i := 0; i < 100; i++ {
+		if d := s.nextDelay(); d < time.Hour || d >= time.Hour+10*time.Minute {
+			t.Fatalf("delay %v outside [1h, 1h10m)", d)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package gofanatical
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// Site is the identity the feeds are published under. Empty fields fall
+// back to defaultSite, so forks and mirrors only set what differs.
+type Site struct {
+	// Name prefixes the titles of the built-in feeds.
+	Name string `json:"name"`
+	// Link is the channel link of every feed, usually the landing page.
+	Link   string `json:"link"`
+	Author string `json:"author"`
+	Email  string `json:"email"`
+	// StoreURL is prepended to bundle paths such as /en/bundle/<slug> in
+	// item links and notifications.
+	StoreURL string `json:"store_url"`
+	// Image is the channel image (logo) of every feed. Empty means none.
+	Image string `json:"image"`
+	// Language is the channel language, e.g. "en-us". Empty omits it.
+	Language string `json:"language"`
+	// Locale is the store locale of links and item labels, e.g. "de".
+	// Empty means defaultLocale.
+	Locale string `json:"locale"`
+	// Locales lists extra locales to publish every feed in, as
+	// <name>.<locale>.rss.
+	Locales []string `json:"locales"`
+	// Feeds overrides the metadata of single feeds, built-in ones
+	// included, by feed name.
+	Feeds map[string]FeedMetadata `json:"feeds"`
+}
+
+// FeedMetadata overrides how one feed presents itself. Empty fields keep
+// the feed's own value.
+type FeedMetadata struct {
+	Title               string `json:"title"`
+	Description         string `json:"description"`
+	Image               string `json:"image"`
+	Language            string `json:"language"`
+	ContentTemplate     string `json:"content_template"`
+	DescriptionTemplate string `json:"description_template"`
+}
+
+// defaultSite is the identity of the upstream GitHub Pages site.
+var defaultSite = Site{
+	Name:     "Fanatical RSS",
+	Link:     "https://feuerlord2.github.io/Fanatical-RSS-Site/",
+	Author:   "Daniel Winter",
+	Email:    "DanielWinterEmsdetten+rss@gmail.com",
+	StoreURL: "https://www.fanatical.com",
+}
+
+// withDefaults fills the empty fields from defaultSite.
+func (s Site) withDefaults() Site {
+	if s.Name == "" {
+		s.Name = defaultSite.Name
+	}
+	if s.Link == "" {
+		s.Link = defaultSite.Link
+	}
+	if s.Author == "" {
+		s.Author = defaultSite.Author
+	}
+	if s.Email == "" {
+		s.Email = defaultSite.Email
+	}
+	if s.StoreURL == "" {
+		s.StoreURL = defaultSite.StoreURL
+	}
+	s.StoreURL = strings.TrimRight(s.StoreURL, "/")
+	if s.Locale == "" {
+		s.Locale = defaultLocale
+	}
+	return s
+}
+
+// locale returns the site's store locale. Locales are validated when the
+// config is loaded; anything else falls back to defaultLocale.
+func (s Site) locale() locale {
+	l, err := lookupLocale(s.withDefaults().Locale)
+	if err != nil {
+		return locales[defaultLocale]
+	}
+	return l
+}
+
+// inLocale returns s switched to the store locale code. Its feeds get the
+// locale's channel language.
+func (s Site) inLocale(code string) Site {
+	s.Locale = code
+	s.Language = locales[code].language
+	return s
+}
+
+// bundleURL returns the absolute store link of b in the site's locale.
+func (s Site) bundleURL(b FanaticalBundle) string {
+	s = s.withDefaults()
+	return s.StoreURL + localizedPath(b.URL, s.Locale)
+}
+
+// LoadSite returns the site identity from the config file, with defaults
+// applied.
+func LoadSite() (Site, error) {
+	cfg, err := loadConfig()
+	return cfg.Site, err
+}
+
+// applyFeedMetadata applies the site's per-feed overrides to def and
+// fills its image and language from the site-wide values.
+func (s Site) applyFeedMetadata(def *FeedDefinition) {
+	if m, ok := s.Feeds[def.Name]; ok {
+		if m.Title != "" {
+			def.Title = m.Title
+		}
+		if m.Description != "" {
+			def.Description = m.Description
+		}
+		if m.Image != "" {
+			def.Image = m.Image
+		}
+		if m.Language != "" {
+			def.Language = m.Language
+		}
+		if m.ContentTemplate != "" {
+			def.ContentTemplate = m.ContentTemplate
+		}
+		if m.DescriptionTemplate != "" {
+			def.DescriptionTemplate = m.DescriptionTemplate
+		}
+	}
+	if def.Image == "" {
+		def.Image = s.Image
+	}
+	if def.Language == "" {
+		def.Language = s.Language
+	}
+	def.site = s
+}
+
+// channel returns the feed skeleton for def: title, description, and the
+// site's link, author, and image.
+func (def FeedDefinition) channel() feeds.Feed {
+	site := def.site.withDefaults()
+	feed := feeds.Feed{
+		Title:       def.Title,
+		Link:        &feeds.Link{Href: site.Link},
+		Description: def.Description,
+		Author:      &feeds.Author{Name: site.Author, Email: site.Email},
+	}
+	if def.Image != "" {
+		feed.Image = &feeds.Image{Url: def.Image, Title: def.Title, Link: site.Link}
+	}
+	return feed
+}
+
+// renderRSS renders feed as RSS 2.0. gorilla/feeds has no language field
+// on Feed, so a language is set on the RSS channel directly.
+func renderRSS(feed feeds.Feed, language string) (string, error) {
+	var (
+		rss string
+		err error
+	)
+	if language == "" {
+		rss, err = feed.ToRss()
+	} else {
+		channel := (&feeds.Rss{Feed: &feed}).RssFeed()
+		channel.Language = language
+		rss, err = feeds.ToXML(channel)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate RSS content: %w", err)
+	}
+	return rss, nil
+}
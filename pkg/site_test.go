@@ -0,0 +1,104 @@
+package gofanatical
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPublishesUnderSiteIdentity(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	stubBundlesAPI(t, fmt.Sprintf(`[{"name": "Killer Bundle 42", "slug": "killer-42", "type": "bundle",
+		"on_sale": true, "price": {"USD": 4.99}, "fullPrice": {"USD": 49.99},
+		"available_valid_from": 1000, "available_valid_until": %d}]`, now.Add(72*time.Hour).Unix()))
+	writeConfig(t, `{
+		"site": {
+			"name": "Deal Mirror",
+			"link": "https://deals.example.org/",
+			"author": "Mirror Team",
+			"email": "rss@example.org",
+			"store_url": "https://store.example.org/",
+			"image": "https://deals.example.org/logo.png",
+			"language": "en-us",
+			"feeds": {
+				"games": {"title": "Cheap Games", "description": "Games only", "image": "https://deals.example.org/games.png", "language": "de-de"}
+			}
+		},
+		"feeds": [{"name": "cheap", "query": "price < 5"}]
+	}`)
+	out := t.TempDir()
+
+	if _, err := Run(Options{Clock: func() time.Time { return now }, OutDir: out}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	games := read("games.rss")
+	for _, want := range []string{
+		"<title>Cheap Games</title>",
+		"<description>Games only</description>",
+		"<link>https://deals.example.org/</link>",
+		"<managingEditor>rss@example.org (Mirror Team)</managingEditor>",
+		"<language>de-de</language>",
+		"<url>https://deals.example.org/games.png</url>",
+		"<link>https://store.example.org/en/bundle/killer-42</link>",
+		"<a href='https://store.example.org/en/bundle/killer-42'",
+	} {
+		if !strings.Contains(games, want) {
+			t.Errorf("games.rss is missing %s:\n%s", want, games)
+		}
+	}
+	if strings.Contains(games, "fanatical.com/en/bundle") || strings.Contains(games, "Daniel Winter") {
+		t.Error("games.rss still uses the default identity")
+	}
+
+	books := read("books.rss")
+	for _, want := range []string{"<title>Deal Mirror Books Bundles</title>", "<language>en-us</language>", "<url>https://deals.example.org/logo.png</url>"} {
+		if !strings.Contains(books, want) {
+			t.Errorf("books.rss is missing %s", want)
+		}
+	}
+	if cheap := read("cheap.rss"); !strings.Contains(cheap, "<title>Deal Mirror: cheap</title>") {
+		t.Errorf("custom feed title does not use the site name:\n%s", cheap)
+	}
+}
+
+func TestSiteDefaults(t *testing.T) {
+	feed := createFeed([]FanaticalBundle{testBundle("killer-42", time.Unix(1000, 0))}, categoryFeed(Site{}, "games"))
+	rss, err := renderRSS(feed, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<title>Fanatical RSS Games Bundles</title>",
+		"<link>https://feuerlord2.github.io/Fanatical-RSS-Site/</link>",
+		"<managingEditor>DanielWinterEmsdetten+rss@gmail.com (Daniel Winter)</managingEditor>",
+		"<link>https://www.fanatical.com/en/bundle/killer-42</link>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("default feed is missing %s", want)
+		}
+	}
+	if strings.Contains(rss, "<language>") || strings.Contains(rss, "<image>") {
+		t.Errorf("default feed has a language or image:\n%s", rss)
+	}
+
+	writeConfig(t, `{"site": {"feeds": {"movies": {"title": "Movies"}}}}`)
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := feedDefinitions(cfg, time.Now()); err == nil || !strings.Contains(err.Error(), `unknown feed "movies"`) {
+		t.Errorf("override of an unknown feed: %v", err)
+	}
+}
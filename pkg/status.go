@@ -0,0 +1,115 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+)
+
+// statusFile is published next to the feeds after every run, so
+// subscribers can tell a stale feed from a quiet week.
+const statusFile = "status.json"
+
+// runStatus is the content of status.json.
+type runStatus struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Success is false when any part of the run failed.
+	Success bool `json:"success"`
+	// LastFetch is the time of the last run that fetched bundles. It is
+	// carried over from the previous status when fetching fails.
+	LastFetch         *time.Time     `json:"last_fetch,omitempty"`
+	NewestBundleStart *time.Time     `json:"newest_bundle_start,omitempty"`
+	APILatencySeconds float64        `json:"api_latency_seconds,omitempty"`
+	Bundles           int            `json:"bundles"`
+	Feeds             map[string]int `json:"feeds"`
+}
+
+// newRunStatus describes a finished run. After a failed fetch the feeds
+// on disk are still the previous run's, so their counts are kept.
+func newRunStatus(result Result, success bool, now time.Time, prev runStatus) runStatus {
+	status := runStatus{
+		GeneratedAt:       now.UTC(),
+		Success:           success,
+		APILatencySeconds: math.Round(result.FetchDuration.Seconds()*1000) / 1000,
+		Feeds:             map[string]int{},
+	}
+	if result.Bundles == nil {
+		status.LastFetch = prev.LastFetch
+		status.NewestBundleStart = prev.NewestBundleStart
+		status.Bundles = prev.Bundles
+		if prev.Feeds != nil {
+			status.Feeds = prev.Feeds
+		}
+		return status
+	}
+
+	fetched := now.UTC()
+	status.LastFetch = &fetched
+	status.Bundles = len(result.Bundles)
+	for _, b := range result.Bundles {
+		if status.NewestBundleStart == nil || b.StartDate.After(*status.NewestBundleStart) {
+			start := b.StartDate.UTC()
+			status.NewestBundleStart = &start
+		}
+	}
+	for _, fr := range result.Feeds {
+		status.Feeds[fr.Name] = fr.Items
+	}
+	return status
+}
+
+// publishStatus writes status.json for the finished run.
+func publishStatus(publisher Publisher, result Result, success bool, now time.Time) error {
+	var prev runStatus
+	if data, err := publisher.Fetch(statusFile); err != nil {
+		slog.Warn("failed to read previous status", "error", err)
+	} else if data != nil {
+		if err := json.Unmarshal(data, &prev); err != nil {
+			slog.Warn("ignoring invalid previous status", "file", publisher.Location(statusFile), "error", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(newRunStatus(result, success, now, prev), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+	if _, err := publisher.Publish(statusFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to publish status: %w", err)
+	}
+	return nil
+}
+
+// health is the response body of /healthz.
+type health struct {
+	Status     string     `json:"status"`
+	LastFetch  *time.Time `json:"last_fetch,omitempty"`
+	AgeSeconds int64      `json:"age_seconds,omitempty"`
+	StaleAfter int64      `json:"stale_after_seconds"`
+}
+
+// serveHealth reports 200 while the last successful fetch is younger than
+// the staleness threshold, and 503 before the first fetch or once it is
+// older.
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	h := health{Status: "starting", StaleAfter: int64(s.opts.StaleAfter.Seconds())}
+	code := http.StatusServiceUnavailable
+	if snap := s.snapshot(); snap != nil {
+		age := s.opts.Run.now().Sub(snap.at)
+		at := snap.at.UTC()
+		h.LastFetch = &at
+		h.AgeSeconds = int64(age.Seconds())
+		h.Status = "stale"
+		if age <= s.opts.StaleAfter {
+			h.Status = "ok"
+			code = http.StatusOK
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType(statusFile))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(h)
+}
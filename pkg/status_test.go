@@ -0,0 +1,106 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunPublishesStatus(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	out := t.TempDir()
+	opts := Options{Clock: func() time.Time { return now }, OutDir: out}
+	stubBundlesAPI(t, fmt.Sprintf(`[
+		{"name": "Killer Bundle 42", "slug": "killer-42", "type": "bundle", "on_sale": true,
+		 "price": {"USD": 4.99}, "available_valid_from": 1000, "available_valid_until": %d},
+		{"name": "Later Bundle", "slug": "later", "type": "bundle", "on_sale": true,
+		 "price": {"USD": 1.99}, "available_valid_from": 5000, "available_valid_until": %[1]d}
+	]`, now.Add(72*time.Hour).Unix()))
+	writeConfig(t, `{}`)
+
+	readStatus := func() runStatus {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(out, statusFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var status runStatus
+		if err := json.Unmarshal(data, &status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+
+	if _, err := Run(opts); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	status := readStatus()
+	if !status.Success || !status.GeneratedAt.Equal(now) || status.LastFetch == nil || !status.LastFetch.Equal(now) {
+		t.Errorf("status after success: %+v", status)
+	}
+	if status.NewestBundleStart == nil || !status.NewestBundleStart.Equal(time.Unix(5000, 0)) || status.Bundles != 2 {
+		t.Errorf("newest bundle %v, bundles %d", status.NewestBundleStart, status.Bundles)
+	}
+	if status.Feeds["games"] != 2 || status.Feeds["books"] != 0 || len(status.Feeds) != 4 {
+		t.Errorf("feed counts: %v", status.Feeds)
+	}
+
+	// A failed fetch is reported, while the last fetch and the counts of
+	// the feeds still online are kept.
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+	bundlesURL = failing.URL
+	later := now.Add(6 * time.Hour)
+	opts.Clock = func() time.Time { return later }
+	if _, err := Run(opts); err == nil {
+		t.Fatal("expected Run to fail")
+	}
+	status = readStatus()
+	if status.Success || !status.GeneratedAt.Equal(later) || status.LastFetch == nil || !status.LastFetch.Equal(now) {
+		t.Errorf("status after failure: %+v", status)
+	}
+	if status.Feeds["games"] != 2 || status.Bundles != 2 {
+		t.Errorf("counts not carried over: %v, %d bundles", status.Feeds, status.Bundles)
+	}
+}
+
+func TestServerHealth(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s := &Server{opts: ServeOptions{
+		Run:        Options{Clock: func() time.Time { return now }},
+		StaleAfter: 12 * time.Hour,
+	}}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	check := func(wantCode int, wantStatus string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var h health
+		if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != wantCode || h.Status != wantStatus {
+			t.Errorf("at %v: %d %+v, want %d %s", now, resp.StatusCode, h, wantCode, wantStatus)
+		}
+	}
+
+	check(http.StatusServiceUnavailable, "starting")
+	s.setSnapshot(nil, now)
+	check(http.StatusOK, "ok")
+	now = now.Add(12 * time.Hour)
+	check(http.StatusOK, "ok")
+	now = now.Add(time.Second)
+	check(http.StatusServiceUnavailable, "stale")
+}
@@ -0,0 +1,117 @@
+package gofanatical
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// rssValidationDocument holds the parts of an RSS 2.0 document that
+// ValidateFeed checks.
+type rssValidationDocument struct {
+	XMLName xml.Name
+	Version string `xml:"version,attr"`
+	Channel *struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Items       []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			GUID        string `xml:"guid"`
+			PubDate     string `xml:"pubDate"`
+			Enclosure   *struct {
+				URL    string `xml:"url,attr"`
+				Length string `xml:"length,attr"`
+				Type   string `xml:"type,attr"`
+			} `xml:"enclosure"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// ValidateFeedFile reads and validates an RSS file.
+func ValidateFeedFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read feed: %w", err)
+	}
+	return ValidateFeed(data)
+}
+
+// ValidateFeed checks that data is an RSS 2.0 document feed readers will
+// accept: required channel elements, absolute links, unique GUIDs, and
+// RFC 1123 dates. It reports every problem, not just the first.
+func ValidateFeed(data []byte) error {
+	var doc rssValidationDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("not well-formed XML: %w", err)
+	}
+	if doc.XMLName.Local != "rss" || doc.Version != "2.0" {
+		return fmt.Errorf("root element is <%s version=%q>, want <rss version=\"2.0\">", doc.XMLName.Local, doc.Version)
+	}
+	if doc.Channel == nil {
+		return errors.New("missing <channel>")
+	}
+
+	var errs []error
+	ch := doc.Channel
+	for _, field := range [][2]string{{"title", ch.Title}, {"link", ch.Link}, {"description", ch.Description}} {
+		if field[1] == "" {
+			errs = append(errs, fmt.Errorf("channel: missing <%s>", field[0]))
+		}
+	}
+	if ch.Link != "" && !isAbsoluteURL(ch.Link) {
+		errs = append(errs, fmt.Errorf("channel: link %q is not an absolute URL", ch.Link))
+	}
+
+	seen := map[string]int{}
+	for i, item := range ch.Items {
+		where := fmt.Sprintf("item %d", i+1)
+		if item.GUID != "" {
+			where += fmt.Sprintf(" (%s)", item.GUID)
+		}
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("%s: %s", where, fmt.Sprintf(format, args...)))
+		}
+
+		if item.Title == "" && item.Description == "" {
+			fail("needs a <title> or <description>")
+		}
+		if item.Link != "" && !isAbsoluteURL(item.Link) {
+			fail("link %q is not an absolute URL", item.Link)
+		}
+		switch prev, dup := seen[item.GUID]; {
+		case item.GUID == "":
+			fail("missing <guid>")
+		case dup:
+			fail("same <guid> as item %d", prev)
+		default:
+			seen[item.GUID] = i + 1
+		}
+		if item.PubDate != "" {
+			if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+				if _, err := time.Parse(time.RFC1123, item.PubDate); err != nil {
+					fail("pubDate %q is not an RFC 1123 date", item.PubDate)
+				}
+			}
+		}
+		if e := item.Enclosure; e != nil {
+			if !isAbsoluteURL(e.URL) {
+				fail("enclosure url %q is not an absolute URL", e.URL)
+			}
+			if e.Length == "" || e.Type == "" {
+				fail("enclosure needs length and type")
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,61 @@
+package gofanatical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateFeedAcceptsGeneratedFeeds(t *testing.T) {
+	bundle := testBundle("killer-42", time.Unix(1000, 0))
+	bundle.Image = "https://fanatical.imgix.net/cover.jpg"
+	feed := createFeed([]FanaticalBundle{bundle}, categoryFeed(defaultSite, "games"))
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateFeed([]byte(rss)); err != nil {
+		t.Errorf("generated feed is invalid: %v", err)
+	}
+}
+
+func TestValidateFeedReportsProblems(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		rss  string
+		want []string
+	}{
+		{"not XML", `<rss version="2.0"><channel>`, []string{"not well-formed XML"}},
+		{"Atom", `<feed xmlns="http://www.w3.org/2005/Atom"></feed>`, []string{`root element is <feed version="">`}},
+		{"no channel", `<rss version="2.0"></rss>`, []string{"missing <channel>"}},
+		{"items", `<rss version="2.0"><channel>
+			<title>T</title><link>/relative</link>
+			<item><title>A</title><link>https://example.com/a</link><guid>a</guid><pubDate>2030-03-01</pubDate></item>
+			<item><link>https://example.com/b</link><guid>a</guid></item>
+			<item><title>C</title><enclosure url="cover.jpg"/></item>
+		</channel></rss>`, []string{
+			"channel: missing <description>",
+			`channel: link "/relative" is not an absolute URL`,
+			`item 1 (a): pubDate "2030-03-01" is not an RFC 1123 date`,
+			"item 2 (a): needs a <title> or <description>",
+			"item 2 (a): same <guid> as item 1",
+			"item 3: missing <guid>",
+			`item 3: enclosure url "cover.jpg" is not an absolute URL`,
+			"item 3: enclosure needs length and type",
+		}},
+	} {
+		err := ValidateFeed([]byte(tt.rss))
+		if err == nil {
+			t.Errorf("%s: expected errors", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: %q missing from:\n%v", tt.name, want, err)
+			}
+		}
+		if got := len(strings.Split(err.Error(), "\n")); len(tt.want) > 1 && got != len(tt.want) {
+			t.Errorf("%s: %d problems reported, want %d:\n%v", tt.name, got, len(tt.want), err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package gofanatical
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Watchlist is a personal list of titles, franchises, keywords, or slugs.
+// Each watchlist gets its own feed, docs/watch-<name>.rss, and can alert
+// its own Discord channel or webhooks when a matching bundle appears.
+type Watchlist struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	// Terms match case-insensitively anywhere in the bundle name, or the
+	// whole slug.
+	Terms []string `json:"terms"`
+	// Discord and Webhooks are notified about the watch feed's changes
+	// only. Unlike the site-wide Discord config, the webhook URL is
+	// required, so alerts never go to the shared channel by accident.
+	Discord  *DiscordConfig `json:"discord"`
+	Webhooks *WebhookConfig `json:"webhooks"`
+}
+
+// watchFeedPrefix keeps watch feeds apart from the other feed names.
+const watchFeedPrefix = "watch-"
+
+func (w Watchlist) feedName() string { return watchFeedPrefix + w.Name }
+
+// validate checks w when the config is loaded.
+func (w Watchlist) validate() error {
+	if !feedNamePattern.MatchString(w.Name) {
+		return fmt.Errorf("watchlist: invalid name %q (use lowercase letters, digits, '.', '-', '_')", w.Name)
+	}
+	if len(w.Terms) == 0 {
+		return fmt.Errorf("watchlist %s: at least one term is required", w.Name)
+	}
+	for _, term := range w.Terms {
+		if strings.TrimSpace(term) == "" {
+			return fmt.Errorf("watchlist %s: empty term", w.Name)
+		}
+	}
+	if w.Discord != nil && w.Discord.WebhookURL == "" {
+		return fmt.Errorf("watchlist %s: discord.webhook_url is required", w.Name)
+	}
+	return nil
+}
+
+// query translates the terms into the feed query language:
+//
+//	["Warhammer", "humble-tech"]
+//	→ "warhammer" in title || slug == "warhammer" || "humble-tech" in title || slug == "humble-tech"
+func (w Watchlist) query() string {
+	var alternatives []string
+	for _, term := range w.Terms {
+		term = strings.TrimSpace(term)
+		quoted := quoteQueryString(term)
+		alternatives = append(alternatives, quoted+" in title || slug == "+quoted)
+	}
+	return strings.Join(alternatives, " || ")
+}
+
+// watchlistFeed returns the feed definition of w.
+func watchlistFeed(w Watchlist, site Site) (FeedDefinition, error) {
+	def := FeedDefinition{
+		Name:        w.feedName(),
+		Title:       w.Title,
+		Description: "Fanatical bundles matching: " + strings.Join(w.Terms, ", "),
+		Query:       w.query(),
+	}
+	if def.Title == "" {
+		def.Title = site.withDefaults().Name + " watchlist: " + w.Name
+	}
+	var err error
+	if def.match, err = compileQuery(def.Query); err != nil {
+		return def, fmt.Errorf("watchlist %s: %w", w.Name, err)
+	}
+	return def, nil
+}
+
+// watcher holds the notifiers of one watchlist.
+type watcher struct {
+	feed      string
+	notifiers []Notifier
+}
+
+// watchlistNotifiers returns the watchlists that notify, with their
+// notifiers.
+func watchlistNotifiers(cfg Config) ([]watcher, error) {
+	var list []watcher
+	for _, w := range cfg.Watchlists {
+		wt := watcher{feed: w.feedName()}
+		if w.Discord != nil {
+			n, err := NewDiscordNotifier(*w.Discord)
+			if err != nil {
+				return nil, fmt.Errorf("watchlist %s: %w", w.Name, err)
+			}
+			n.site = cfg.Site
+			wt.notifiers = append(wt.notifiers, n)
+		}
+		if w.Webhooks != nil {
+			n, err := NewWebhookNotifier(*w.Webhooks)
+			if err != nil {
+				return nil, fmt.Errorf("watchlist %s: %w", w.Name, err)
+			}
+			n.site = cfg.Site
+			wt.notifiers = append(wt.notifiers, n)
+		}
+		if len(wt.notifiers) > 0 {
+			list = append(list, wt)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,136 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchlistFeedMatchesTermsInNameOrSlug(t *testing.T) {
+	def, err := watchlistFeed(Watchlist{Name: "mine", Terms: []string{"Warhammer", " humble-tech "}}, defaultSite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Name != "watch-mine" || def.Title != "Fanatical RSS watchlist: mine" {
+		t.Errorf("def = %q %q, want watch-mine with the default title", def.Name, def.Title)
+	}
+
+	for slug, name := range map[string]string{
+		"wh-40k":      "WARHAMMER 40,000 Bundle",
+		"humble-tech": "Tech Essentials",
+	} {
+		b := testBundle(slug, time.Unix(1000, 0))
+		b.Title = name
+		if !def.match(b) {
+			t.Errorf("%s (%s) should match", slug, name)
+		}
+	}
+	for slug, name := range map[string]string{
+		"humble-tech-2": "Tech Essentials 2",
+		"sims":          "Sims Collection",
+	} {
+		b := testBundle(slug, time.Unix(1000, 0))
+		b.Title = name
+		if def.match(b) {
+			t.Errorf("%s (%s) should not match", slug, name)
+		}
+	}
+}
+
+func TestWatchlistTermsAreMatchedLiterally(t *testing.T) {
+	def, err := watchlistFeed(Watchlist{Name: "odd", Terms: []string{"tab\tname", `"quoted" \ deal`}}, defaultSite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for title, want := range map[string]bool{
+		"The tab\tname bundle":         true,
+		"The tabtname bundle":          false,
+		`A "Quoted" \ Deal Collection`: true,
+	} {
+		b := testBundle("x", time.Unix(1000, 0))
+		b.Title = title
+		if got := def.match(b); got != want {
+			t.Errorf("match(%q) = %v, want %v", title, got, want)
+		}
+	}
+}
+
+func TestWatchlistValidation(t *testing.T) {
+	for _, tc := range []struct {
+		w    Watchlist
+		want string
+	}{
+		{Watchlist{Name: "Bad Name", Terms: []string{"x"}}, "invalid name"},
+		{Watchlist{Name: "empty"}, "at least one term"},
+		{Watchlist{Name: "blank", Terms: []string{"x", " "}}, "empty term"},
+		{Watchlist{Name: "shared", Terms: []string{"x"}, Discord: &DiscordConfig{}}, "webhook_url is required"},
+	} {
+		err := tc.w.validate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("validate(%+v) = %v, want %q", tc.w, err, tc.want)
+		}
+	}
+}
+
+// TestRunWatchlistFeedAndAlerts checks that a watchlist gets its own feed
+// and that its webhook only hears about matching bundles, and not about
+// the backlog on the first publish.
+func TestRunWatchlistFeedAndAlerts(t *testing.T) {
+	var payloads []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p webhookPayload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		payloads = append(payloads, p)
+	}))
+	defer server.Close()
+
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	until := now.Add(72 * time.Hour).Unix()
+	bundle := func(name, slug string, start int) string {
+		return fmt.Sprintf(`{"name": %q, "slug": %q, "type": "bundle", "on_sale": true,
+			"price": {"USD": 4.99}, "fullPrice": {"USD": 49.99},
+			"available_valid_from": %d, "available_valid_until": %d}`, name, slug, start, until)
+	}
+	writeConfig(t, fmt.Sprintf(`{"watchlists": [
+		{"name": "warhammer", "terms": ["warhammer"], "webhooks": {"urls": [%q], "secret": "s3cret"}}
+	]}`, server.URL))
+	out := t.TempDir()
+	opts := Options{Clock: func() time.Time { return now }, OutDir: out}
+
+	stubBundlesAPI(t, "["+bundle("Warhammer Classics", "wh-classics", 1000)+","+bundle("Puzzle Pack", "puzzles", 2000)+"]")
+	if _, err := Run(opts); err != nil {
+		t.Fatalf("first Run failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "watch-warhammer.rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed := string(data); !strings.Contains(feed, "wh-classics") || strings.Contains(feed, "puzzles") {
+		t.Errorf("watch feed should hold only the Warhammer bundle:\n%s", feed)
+	}
+	if len(payloads) != 0 {
+		t.Fatalf("first publish sent %d payloads, want none", len(payloads))
+	}
+
+	stubBundlesAPI(t, "["+bundle("Warhammer Classics", "wh-classics", 1000)+","+bundle("Puzzle Pack", "puzzles", 2000)+","+
+		bundle("Warhammer 40,000 Bundle", "wh-40k", 3000)+","+bundle("Racing Bundle", "racing", 4000)+"]")
+	if _, err := Run(opts); err != nil {
+		t.Fatalf("second Run failed: %v", err)
+	}
+	if len(payloads) != 1 {
+		t.Fatalf("got %d payloads, want 1", len(payloads))
+	}
+	if p := payloads[0]; len(p.New) != 1 || p.New[0].Slug != "wh-40k" || len(p.Changed) != 0 || len(p.Removed) != 0 {
+		t.Errorf("payload = %+v, want only the new Warhammer bundle", p)
+	}
+}
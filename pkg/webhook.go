@@ -0,0 +1,262 @@
+package gofanatical
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WebhookConfig enables posting change events as signed JSON to
+// arbitrary HTTP endpoints.
+type WebhookConfig struct {
+	URLs []string `json:"urls"`
+	// Secret keys the HMAC-SHA256 signature; when empty, WEBHOOK_SECRET
+	// is used.
+	Secret string `json:"secret"`
+	// DeadLetterFile collects deliveries that failed every attempt. Empty
+	// means defaultDeadLetterFile.
+	DeadLetterFile string `json:"dead_letter_file"`
+}
+
+const (
+	defaultDeadLetterFile  = "webhook-dead-letter.jsonl"
+	webhookAttempts        = 4
+	webhookSignatureHeader = "X-Gofanatical-Signature-256"
+)
+
+// WebhookNotifier delivers one payload per run to every configured URL.
+type WebhookNotifier struct {
+	cfg    WebhookConfig
+	site   Site
+	client *http.Client
+	sleep  func(time.Duration)
+}
+
+// NewWebhookNotifier validates cfg and resolves the secret from the
+// environment.
+func NewWebhookNotifier(cfg WebhookConfig) (*WebhookNotifier, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, fmt.Errorf("webhooks: at least one URL is required")
+	}
+	if cfg.Secret == "" {
+		cfg.Secret = os.Getenv("WEBHOOK_SECRET")
+	}
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("webhooks: secret or WEBHOOK_SECRET is required")
+	}
+	if cfg.DeadLetterFile == "" {
+		cfg.DeadLetterFile = defaultDeadLetterFile
+	}
+	return &WebhookNotifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		sleep:  time.Sleep,
+	}, nil
+}
+
+// Name implements Notifier.
+func (w *WebhookNotifier) Name() string { return "webhooks" }
+
+type webhookPayload struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	New         []webhookBundle `json:"new"`
+	Changed     []webhookBundle `json:"changed"`
+	// Removed lists deals that are no longer on sale. Bundles a feed
+	// limit cut are still on sale and never show up here.
+	Removed []webhookItem `json:"removed"`
+}
+
+type webhookBundle struct {
+	GUID             string       `json:"guid"`
+	Slug             string       `json:"slug"`
+	Title            string       `json:"title"`
+	URL              string       `json:"url"`
+	Image            string       `json:"image,omitempty"`
+	Category         string       `json:"category"`
+	StartDate        time.Time    `json:"start_date"`
+	EndDate          time.Time    `json:"end_date"`
+	Price            webhookPrice `json:"price"`
+	OperatingSystems []string     `json:"operating_systems,omitempty"`
+	DRM              []string     `json:"drm,omitempty"`
+}
+
+type webhookPrice struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Original float64 `json:"original"`
+	Discount int     `json:"discount"`
+}
+
+type webhookItem struct {
+	GUID  string `json:"guid"`
+	Title string `json:"title"`
+}
+
+type deadLetter struct {
+	URL      string          `json:"url"`
+	FailedAt time.Time       `json:"failed_at"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// Notify implements Notifier. A delivery that fails every attempt is
+// appended to the dead-letter file and does not fail the run; only a
+// dead letter that cannot be written is reported as an error.
+func (w *WebhookNotifier) Notify(events Events) error {
+	if events.Empty() {
+		return nil
+	}
+	body, err := json.Marshal(newWebhookPayload(events, w.site))
+	if err != nil {
+		return fmt.Errorf("webhooks: failed to encode payload: %w", err)
+	}
+
+	var failed []deadLetter
+	for _, u := range w.cfg.URLs {
+		attempts, err := w.deliver(u, body)
+		if err != nil {
+			slog.Error("webhook delivery failed", "url", u, "attempts", attempts, "error", err)
+			failed = append(failed, deadLetter{
+				URL:      u,
+				FailedAt: events.At.UTC(),
+				Attempts: attempts,
+				Error:    err.Error(),
+				Payload:  body,
+			})
+		}
+	}
+	if err := w.writeDeadLetters(failed); err != nil {
+		return fmt.Errorf("webhooks: %w", err)
+	}
+	return nil
+}
+
+func newWebhookPayload(events Events, site Site) webhookPayload {
+	payload := webhookPayload{
+		GeneratedAt: events.At.UTC(),
+		New:         []webhookBundle{},
+		Changed:     []webhookBundle{},
+		Removed:     []webhookItem{},
+	}
+	for _, b := range events.New {
+		payload.New = append(payload.New, webhookBundleFor(b, site))
+	}
+	for _, b := range events.Changed {
+		payload.Changed = append(payload.Changed, webhookBundleFor(b, site))
+	}
+	for _, item := range events.Removed {
+		payload.Removed = append(payload.Removed, webhookItem{GUID: item.GUID, Title: item.Title})
+	}
+	return payload
+}
+
+func webhookBundleFor(b FanaticalBundle, site Site) webhookBundle {
+	return webhookBundle{
+		GUID:             bundleGUID(b),
+		Slug:             b.Slug,
+		Title:            b.Title,
+		URL:              site.bundleURL(b),
+		Image:            b.Image,
+		Category:         b.Category,
+		StartDate:        b.StartDate.UTC(),
+		EndDate:          b.EndDate.UTC(),
+		Price:            webhookPrice(b.Price),
+		OperatingSystems: b.OperatingSystems,
+		DRM:              b.DRM,
+	}
+}
+
+// deliver posts body to url, retrying network errors and 5xx/429
+// responses with a growing delay. It returns the number of attempts made.
+func (w *WebhookNotifier) deliver(url string, body []byte) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := w.post(url, body)
+		if err == nil {
+			return attempt, nil
+		}
+		if attempt >= webhookAttempts || !isRetryable(err) {
+			return attempt, err
+		}
+		w.sleep(time.Duration(attempt*attempt) * time.Second)
+	}
+}
+
+type webhookStatusError struct {
+	status int
+}
+
+func (e webhookStatusError) Error() string {
+	return fmt.Sprintf("endpoint returned status %d", e.status)
+}
+
+func isRetryable(err error) bool {
+	var se webhookStatusError
+	return !errors.As(err, &se) || se.status >= 500 || se.status == http.StatusTooManyRequests
+}
+
+func (w *WebhookNotifier) post(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gofanatical-webhook")
+	req.Header.Set(webhookSignatureHeader, webhookSignature(w.cfg.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return webhookStatusError{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// webhookSignature returns "sha256=" and the hex HMAC-SHA256 of body, the
+// format GitHub uses, so receivers can reuse existing verification code.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *WebhookNotifier) writeDeadLetters(letters []deadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, l := range letters {
+		if err := enc.Encode(l); err != nil {
+			return fmt.Errorf("failed to encode dead letter: %w", err)
+		}
+	}
+
+	path := w.cfg.DeadLetterFile
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file %s: %w", path, err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write dead-letter file %s: %w", path, err)
+	}
+	return f.Close()
+}
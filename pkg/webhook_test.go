@@ -0,0 +1,200 @@
+package gofanatical
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifierSignsAndRetries(t *testing.T) {
+	var bodies [][]byte
+	var signatures []string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get(webhookSignatureHeader))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	n, err := NewWebhookNotifier(WebhookConfig{URLs: []string{server.URL}, Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slept []time.Duration
+	n.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	events := Events{
+		At:      time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC),
+		New:     []FanaticalBundle{testBundle("fresh", time.Unix(2000, 0))},
+		Changed: []FanaticalBundle{testBundle("repriced", time.Unix(1000, 0))},
+		Removed: []DiffItem{{GUID: "fanatical-gone-500", Title: "Bundle gone"}},
+	}
+	if err := n.Notify(events); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if len(slept) != 1 || len(bodies) != 1 {
+		t.Fatalf("expected one retry and one delivery, got %d sleeps and %d deliveries", len(slept), len(bodies))
+	}
+	if want := webhookSignature("s3cret", bodies[0]); signatures[0] != want {
+		t.Errorf("signature = %q, want %q", signatures[0], want)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if len(payload.New) != 1 || payload.New[0].GUID != "fanatical-fresh-2000" ||
+		payload.New[0].URL != "https://www.fanatical.com/en/bundle/fresh" {
+		t.Errorf("new = %+v", payload.New)
+	}
+	if len(payload.Changed) != 1 || payload.Changed[0].Slug != "repriced" || payload.Changed[0].Price.Amount != 4.99 {
+		t.Errorf("changed = %+v", payload.Changed)
+	}
+	if len(payload.Removed) != 1 || payload.Removed[0].GUID != "fanatical-gone-500" {
+		t.Errorf("removed = %+v", payload.Removed)
+	}
+	if !payload.GeneratedAt.Equal(events.At) {
+		t.Errorf("generated_at = %v, want %v", payload.GeneratedAt, events.At)
+	}
+}
+
+func TestWebhookSignatureKnownValue(t *testing.T) {
+	// From GitHub's webhook validation docs.
+	got := webhookSignature("It's a Secret to Everybody", []byte("Hello, World!"))
+	want := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookNotifierDeadLettersFailedDeliveries(t *testing.T) {
+	requests := 0
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	delivered := 0
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered++
+	}))
+	defer healthy.Close()
+
+	deadLetters := filepath.Join(t.TempDir(), "dead.jsonl")
+	t.Setenv("WEBHOOK_SECRET", "from-env")
+	n, err := NewWebhookNotifier(WebhookConfig{URLs: []string{broken.URL, healthy.URL}, DeadLetterFile: deadLetters})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.sleep = func(time.Duration) {}
+
+	events := Events{At: time.Unix(5000, 0), New: []FanaticalBundle{testBundle("x", time.Unix(1000, 0))}}
+	if err := n.Notify(events); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if requests != webhookAttempts || delivered != 1 {
+		t.Errorf("broken endpoint got %d requests, healthy got %d", requests, delivered)
+	}
+
+	data, err := os.ReadFile(deadLetters)
+	if err != nil {
+		t.Fatalf("dead-letter file not written: %v", err)
+	}
+	var letter deadLetter
+	if err := json.Unmarshal(data, &letter); err != nil {
+		t.Fatalf("invalid dead letter: %v\n%s", err, data)
+	}
+	if letter.URL != broken.URL || letter.Attempts != webhookAttempts || letter.Error != "endpoint returned status 503" {
+		t.Errorf("dead letter = %+v", letter)
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(letter.Payload, &payload); err != nil || len(payload.New) != 1 {
+		t.Errorf("dead letter payload not replayable: %v %s", err, letter.Payload)
+	}
+}
+
+func TestWebhookNotifierDoesNotRetryClientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	n, err := NewWebhookNotifier(WebhookConfig{
+		URLs: []string{server.URL}, Secret: "x",
+		DeadLetterFile: filepath.Join(t.TempDir(), "dead.jsonl"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.sleep = func(time.Duration) { t.Error("slept before giving up on a 410") }
+
+	if err := n.Notify(Events{Removed: []DiffItem{{GUID: "g"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request for a 410, got %d", requests)
+	}
+}
+
+func TestRunWebhookNeverRemovesBundlesCutByLimits(t *testing.T) {
+	var payloads []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		payloads = append(payloads, p)
+	}))
+	defer server.Close()
+
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	bundle := func(slug string, start int) string {
+		return fmt.Sprintf(`{"name": "Bundle %s", "slug": %q, "type": "bundle", "display_type": "game-bundle", "on_sale": true,
+			"price": {"USD": 4.99}, "fullPrice": {"USD": 49.99},
+			"available_valid_from": %d, "available_valid_until": %d}`, slug, slug, start, now.Add(72*time.Hour).Unix())
+	}
+	writeConfig(t, fmt.Sprintf(`{
+		"history_file": %q,
+		"feed_limits": {"games": {"max_items": 2}},
+		"webhooks": {"urls": [%q], "secret": "s3cret", "dead_letter_file": %q}
+	}`, filepath.Join(t.TempDir(), "history.jsonl"), server.URL, filepath.Join(t.TempDir(), "dead.jsonl")))
+	opts := Options{Clock: func() time.Time { return now }, OutDir: t.TempDir()}
+
+	// c pushes a out of the games feed while a stays on sale; then c ends.
+	for _, snapshot := range [][]string{{bundle("a", 1000), bundle("b", 2000)},
+		{bundle("a", 1000), bundle("b", 2000), bundle("c", 3000)},
+		{bundle("a", 1000), bundle("b", 2000)}} {
+		stubBundlesAPI(t, "["+strings.Join(snapshot, ",")+"]")
+		if _, err := Run(opts); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		now = now.Add(6 * time.Hour)
+	}
+
+	var removed []string
+	for _, p := range payloads {
+		for _, item := range p.Removed {
+			removed = append(removed, item.GUID)
+		}
+	}
+	if len(removed) != 1 || removed[0] != "fanatical-c-3000" {
+		t.Errorf("removed = %v, want only the ended bundle c", removed)
+	}
+}